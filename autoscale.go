@@ -0,0 +1,233 @@
+package asyncer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Default autoscaling options.
+const (
+	defaultAutoscaleInterval       = 10 * time.Second
+	defaultAutoscaleTargetLatency  = time.Second
+	defaultAutoscaleBacklogPerSlot = 10
+	defaultAutoscaleMaxErrorRate   = 0.5
+	autoscaleMinSamples            = 10
+)
+
+type (
+	// AutoscaleConfig configures adaptive worker concurrency of a queue server.
+	// Zero values are replaced with the defaults.
+	AutoscaleConfig struct {
+		// MinConcurrency is the minimum number of active workers. Default: 1.
+		MinConcurrency int
+		// MaxConcurrency is the maximum number of active workers.
+		// It's capped by the server concurrency (see WithQueueConcurrency), which is also the default.
+		MaxConcurrency int
+		// Interval is the time between scaling decisions. Default: 10 seconds.
+		Interval time.Duration
+		// TargetLatency is the queue latency above which the number of workers grows. Default: 1 second.
+		TargetLatency time.Duration
+		// BacklogPerWorker is the number of pending tasks per active worker above which
+		// the number of workers grows. Default: 10.
+		BacklogPerWorker int
+		// MaxErrorRate is the handler error rate (0..1) above which the number of workers shrinks,
+		// e.g. to relieve a struggling downstream service. Default: 0.5.
+		MaxErrorRate float64
+	}
+
+	// handlerStats collects the handler outcomes of the server for autoscaling.
+	handlerStats struct {
+		mu        sync.Mutex
+		active    int
+		processed int
+		failed    int
+	}
+)
+
+// start records a task taken by a worker.
+func (s *handlerStats) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active++
+}
+
+// done records the handler outcome of a task. Requeued tasks are not counted.
+func (s *handlerStats) done(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	if IsRequeueError(err) {
+		return
+	}
+	s.processed++
+	if err != nil {
+		s.failed++
+	}
+}
+
+// collect returns the number of busy workers and the handler outcomes recorded since the previous call.
+func (s *handlerStats) collect() (active, processed, failed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active, processed, failed = s.active, s.processed, s.failed
+	s.processed, s.failed = 0, 0
+	return active, processed, failed
+}
+
+// handlerStatsCollector returns a middleware which records the handler outcomes for autoscaling.
+func (srv *QueueServer) handlerStatsCollector(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) (err error) {
+		srv.stats.start()
+		defer func() { srv.stats.done(err) }()
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// Concurrency returns the current number of workers, i.e. the number of tasks the server fetches at most.
+func (srv *QueueServer) Concurrency() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return srv.cnf.Concurrency
+}
+
+// Autoscale adjusts the number of workers between the min and max concurrency
+// based on the latency and backlog of the server queues and the handler error rate,
+// until the context is canceled.
+// The server is replaced with a new one using the adjusted concurrency, like on UpdateQueues,
+// so the server never fetches more tasks than it's allowed to process.
+// The in-flight tasks of the replaced server are finished in the background within the shutdown timeout
+// (see WithQueueShutdownTimeout), so the number of processed tasks may exceed a lowered limit for that time.
+// It returns a function that can be used to run the autoscaler in an error group.
+// E.g.:
+//
+//	queueServer := asyncer.NewQueueServer(redisClient, asyncer.WithQueueConcurrency(50))
+//
+//	eg, ctx := errgroup.WithContext(context.Background())
+//	eg.Go(queueServer.Run(handlers...))
+//	eg.Go(queueServer.Autoscale(ctx, asyncer.AutoscaleConfig{MinConcurrency: 5}))
+func (srv *QueueServer) Autoscale(ctx context.Context, cfg AutoscaleConfig) func() error {
+	return func() error {
+		cfg = cfg.withDefaults(srv.workers)
+		if err := srv.setConcurrency(cfg.MaxConcurrency); err != nil {
+			return errors.Join(ErrFailedToAutoscale, err)
+		}
+
+		inspector := asynq.NewInspectorFromRedisClient(srv.redis)
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-srv.done:
+				return nil
+			case <-ticker.C:
+				latency, backlog, err := srv.queuesLoad(inspector)
+				if err != nil {
+					return errors.Join(ErrFailedToAutoscale, err)
+				}
+				active, processed, failed := srv.stats.collect()
+				next := cfg.nextConcurrency(srv.Concurrency(), active, processed, failed, latency, backlog)
+				if err := srv.setConcurrency(next); err != nil {
+					return errors.Join(ErrFailedToAutoscale, err)
+				}
+			}
+		}
+	}
+}
+
+// setConcurrency replaces the server with a new one using the given concurrency, if it differs from the current one.
+func (srv *QueueServer) setConcurrency(concurrency int) error {
+	if srv.Concurrency() == concurrency {
+		return nil
+	}
+	return srv.reconfigure(func(cnf *asynq.Config) {
+		cnf.Concurrency = concurrency
+	})
+}
+
+// queuesLoad returns the maximum latency and the total number of pending tasks of the server queues.
+func (srv *QueueServer) queuesLoad(inspector *asynq.Inspector) (time.Duration, int, error) {
+	srv.mu.Lock()
+	queues := make([]string, 0, len(srv.cnf.Queues))
+	for name := range srv.cnf.Queues {
+		queues = append(queues, name)
+	}
+	srv.mu.Unlock()
+
+	var (
+		latency time.Duration
+		backlog int
+	)
+	for _, queue := range queues {
+		info, err := inspector.GetQueueInfo(queue)
+		if err != nil {
+			if errors.Is(err, asynq.ErrQueueNotFound) {
+				continue
+			}
+			return 0, 0, err
+		}
+		if info.Paused {
+			continue
+		}
+		latency = max(latency, info.Latency)
+		backlog += info.Pending
+	}
+
+	return latency, backlog, nil
+}
+
+// withDefaults returns the config with zero values replaced with the defaults.
+func (cfg AutoscaleConfig) withDefaults(serverConcurrency int) AutoscaleConfig {
+	if cfg.MaxConcurrency < 1 || cfg.MaxConcurrency > serverConcurrency {
+		cfg.MaxConcurrency = serverConcurrency
+	}
+	if cfg.MinConcurrency < 1 {
+		cfg.MinConcurrency = 1
+	}
+	if cfg.MinConcurrency > cfg.MaxConcurrency {
+		cfg.MinConcurrency = cfg.MaxConcurrency
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultAutoscaleInterval
+	}
+	if cfg.TargetLatency <= 0 {
+		cfg.TargetLatency = defaultAutoscaleTargetLatency
+	}
+	if cfg.BacklogPerWorker < 1 {
+		cfg.BacklogPerWorker = defaultAutoscaleBacklogPerSlot
+	}
+	if cfg.MaxErrorRate <= 0 || cfg.MaxErrorRate > 1 {
+		cfg.MaxErrorRate = defaultAutoscaleMaxErrorRate
+	}
+	return cfg
+}
+
+// nextConcurrency returns the number of workers for the next interval.
+// The number shrinks fast when handlers fail, grows fast when the queues fall behind,
+// and shrinks slowly when the workers are mostly idle.
+func (cfg AutoscaleConfig) nextConcurrency(limit, active, processed, failed int, latency time.Duration, backlog int) int {
+	step := max(1, limit/4)
+
+	next := limit
+	switch {
+	case processed >= autoscaleMinSamples && float64(failed)/float64(processed) > cfg.MaxErrorRate:
+		next = limit - step
+	case latency > cfg.TargetLatency || backlog > limit*cfg.BacklogPerWorker:
+		next = limit + step
+	case backlog == 0 && active < limit/2:
+		next = limit - 1
+	}
+
+	return min(max(next, cfg.MinConcurrency), cfg.MaxConcurrency)
+}
@@ -0,0 +1,56 @@
+package asyncer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAutoscaleNextConcurrency(t *testing.T) {
+	cfg := AutoscaleConfig{MinConcurrency: 2, MaxConcurrency: 20}.withDefaults(16)
+
+	tests := []struct {
+		name      string
+		limit     int
+		active    int
+		processed int
+		failed    int
+		latency   time.Duration
+		backlog   int
+		want      int
+	}{
+		{name: "failing handlers shrink fast", limit: 8, active: 8, processed: 20, failed: 15, want: 6},
+		{name: "too few samples to shrink", limit: 8, active: 8, processed: 5, failed: 5, want: 8},
+		{name: "latency grows", limit: 8, active: 8, latency: 2 * time.Second, want: 10},
+		{name: "backlog grows", limit: 8, active: 8, backlog: 81, want: 10},
+		{name: "grows up to the server concurrency", limit: 15, active: 15, backlog: 1000, want: 16},
+		{name: "idle workers shrink slowly", limit: 8, active: 1, want: 7},
+		{name: "shrinks down to the min concurrency", limit: 2, active: 0, want: 2},
+		{name: "steady", limit: 8, active: 6, backlog: 10, want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.nextConcurrency(tt.limit, tt.active, tt.processed, tt.failed, tt.latency, tt.backlog)
+			if got != tt.want {
+				t.Errorf("nextConcurrency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerStats(t *testing.T) {
+	var stats handlerStats
+	for _, err := range []error{nil, errors.New("failed"), &RequeueError{Err: ErrRateLimitExceeded}} {
+		stats.start()
+		stats.done(err)
+	}
+	stats.start()
+
+	if active, processed, failed := stats.collect(); active != 1 || processed != 2 || failed != 1 {
+		t.Errorf("collect() = %d, %d, %d, want 1, 2, 1", active, processed, failed)
+	}
+	if _, processed, failed := stats.collect(); processed != 0 || failed != 0 {
+		t.Errorf("collect() after collect() = _, %d, %d, want the outcomes reset", processed, failed)
+	}
+}
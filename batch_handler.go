@@ -0,0 +1,129 @@
+package asyncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/hibiken/asynq"
+)
+
+// batchPayloadPrefix is the prefix of the payloads of the tasks aggregated by the batch aggregator.
+// The payloads of the group are wrapped into an object, so an aggregated payload is told apart
+// from a payload of a single task, even if the payload of the task is a JSON array.
+const batchPayloadPrefix = `{"asyncer:batch":`
+
+type (
+	// batchHandlerFunc is a function that handles a batch of aggregated tasks.
+	batchHandlerFunc[Payload any] func(context.Context, []Payload) error
+
+	// batchHandlerFuncWrapper is a struct that represents a wrapper for a batch handler function.
+	// It implements the TaskHandler interface.
+	batchHandlerFuncWrapper[Payload any] struct {
+		name string
+		fn   batchHandlerFunc[Payload]
+		opts []TaskOption
+	}
+)
+
+// TaskName returns the name of the task handled by the batchHandlerFuncWrapper.
+func (h *batchHandlerFuncWrapper[Payload]) TaskName() string {
+	return h.name
+}
+
+// Handle unmarshals the aggregated payload into a slice of Payload and calls the wrapped handler function.
+// A task that was not aggregated (e.g. enqueued without the Group option) is passed to the handler
+// as a batch of one element.
+func (h *batchHandlerFuncWrapper[Payload]) Handle(ctx context.Context, payload []byte) error {
+	batch, err := unmarshalBatch[Payload](payload)
+	if err != nil {
+		return err
+	}
+
+	return h.fn(ctx, batch)
+}
+
+// decodePayload unmarshals the payload into a slice of the Payload type of the batch handler function.
+// It's used by the Inspector to decode the payloads of the registered task handlers.
+func (h *batchHandlerFuncWrapper[Payload]) decodePayload(payload []byte) (any, error) {
+	return unmarshalBatch[Payload](payload)
+}
+
+// unmarshalBatch unmarshals the aggregated payload into a slice of the Payload type.
+// A payload that was not aggregated by the batch aggregator is unmarshaled as a batch of one element.
+func unmarshalBatch[Payload any](payload []byte) ([]Payload, error) {
+	var batch []Payload
+
+	trimmed := bytes.TrimSpace(payload)
+	switch {
+	case len(trimmed) == 0:
+		// Nothing to unmarshal, the result is an empty batch.
+	case bytes.HasPrefix(trimmed, []byte(batchPayloadPrefix)):
+		var aggregated struct {
+			Batch []Payload `json:"asyncer:batch"`
+		}
+		if err := json.Unmarshal(trimmed, &aggregated); err != nil {
+			return nil, errors.Join(ErrFailedToUnmarshalPayload, err)
+		}
+		batch = aggregated.Batch
+	default:
+		var p Payload
+		if err := json.Unmarshal(trimmed, &p); err != nil {
+			return nil, errors.Join(ErrFailedToUnmarshalPayload, err)
+		}
+		batch = append(batch, p)
+	}
+
+	return batch, nil
+}
+
+// Options returns the options for the batch handler function.
+func (h *batchHandlerFuncWrapper[Payload]) Options() []asynq.Option {
+	return h.opts
+}
+
+// BatchHandlerFunc creates a TaskHandler for handling aggregated tasks of a specific payload type.
+// Tasks must be enqueued with the Group option, and the queue server must be configured
+// with the batch aggregator (see WithQueueBatchAggregator).
+// The handler receives all payloads of the aggregated group in the order they were enqueued.
+// E.g.:
+//
+//	eg.Go(asyncer.RunQueueServer(
+//		ctx, redisClient, logger,
+//		asyncer.BatchHandlerFunc("notification:send", func(ctx context.Context, batch []Notification) error {
+//			// ... handle batch here ...
+//		}),
+//	))
+//
+//	enqueuer.EnqueueTask(ctx, "notification:send", notification, asyncer.Group("user:42"))
+func BatchHandlerFunc[Payload any](name string, fn batchHandlerFunc[Payload], opts ...TaskOption) TaskHandler {
+	return &batchHandlerFuncWrapper[Payload]{
+		name: name,
+		fn:   fn,
+		opts: opts,
+	}
+}
+
+// aggregateBatch aggregates the tasks of a group into one task.
+// The aggregated task has the type of the first task in the group
+// and its payload is a JSON array of the payloads of all tasks, wrapped into an object (see batchPayloadPrefix).
+// All tasks in a group are expected to have the same type.
+func aggregateBatch(_ string, tasks []*asynq.Task) *asynq.Task {
+	var buf bytes.Buffer
+	buf.WriteString(batchPayloadPrefix)
+	buf.WriteByte('[')
+	for i, t := range tasks {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if p := t.Payload(); len(p) > 0 {
+			buf.Write(p)
+		} else {
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteString("]}")
+
+	return asynq.NewTask(tasks[0].Type(), buf.Bytes())
+}
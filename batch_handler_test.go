@@ -0,0 +1,111 @@
+package asyncer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestUnmarshalBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    [][]int
+		wantErr bool
+	}{
+		{
+			name:    "empty payload",
+			payload: nil,
+			want:    nil,
+		},
+		{
+			name:    "single task with an array payload",
+			payload: []byte(`[1,2]`),
+			want:    [][]int{{1, 2}},
+		},
+		{
+			name:    "aggregated tasks",
+			payload: []byte(`{"asyncer:batch":[[1],[2,3]]}`),
+			want:    [][]int{{1}, {2, 3}},
+		},
+		{
+			name:    "aggregated tasks with surrounding spaces",
+			payload: []byte(" \n{\"asyncer:batch\":[[4]]}\n"),
+			want:    [][]int{{4}},
+		},
+		{
+			name:    "invalid single payload",
+			payload: []byte(`{"a":1}`),
+			wantErr: true,
+		},
+		{
+			name:    "invalid aggregated payload",
+			payload: []byte(`{"asyncer:batch":[1]}`),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalBatch[[]int](tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshalBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmarshalBatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateBatch(t *testing.T) {
+	type payload struct {
+		ID int `json:"id"`
+	}
+
+	tests := []struct {
+		name  string
+		tasks []*asynq.Task
+		want  []*payload
+	}{
+		{
+			name:  "single task",
+			tasks: []*asynq.Task{asynq.NewTask("batch", []byte(`{"id":1}`))},
+			want:  []*payload{{ID: 1}},
+		},
+		{
+			name: "several tasks in order",
+			tasks: []*asynq.Task{
+				asynq.NewTask("batch", []byte(`{"id":1}`)),
+				asynq.NewTask("batch", []byte(`{"id":2}`)),
+			},
+			want: []*payload{{ID: 1}, {ID: 2}},
+		},
+		{
+			name: "task without payload",
+			tasks: []*asynq.Task{
+				asynq.NewTask("batch", nil),
+				asynq.NewTask("batch", []byte(`{"id":3}`)),
+			},
+			want: []*payload{nil, {ID: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := aggregateBatch("group", tt.tasks)
+			if task.Type() != "batch" {
+				t.Errorf("aggregateBatch() type = %q, want %q", task.Type(), "batch")
+			}
+
+			got, err := unmarshalBatch[*payload](task.Payload())
+			if err != nil {
+				t.Fatalf("unmarshalBatch() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmarshalBatch(aggregateBatch()) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
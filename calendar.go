@@ -0,0 +1,269 @@
+package asyncer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+)
+
+// dateLayout is the layout of the calendar dates.
+const dateLayout = "2006-01-02"
+
+// maxExcludedDays is the maximum number of consecutive excluded days a schedule skips or shifts over.
+// A schedule with more excluded days in a row never fires.
+const maxExcludedDays = 366
+
+type (
+	// Calendar is a set of days excluded from schedules (see SkipExcluded and ShiftExcluded).
+	Calendar interface {
+		// Excludes reports whether the day of the given time is excluded.
+		// The day is evaluated in the location of the time, which is the time zone of the schedule.
+		Excludes(t time.Time) bool
+	}
+
+	// CalendarFunc is an adapter to use a function as a Calendar.
+	CalendarFunc func(t time.Time) bool
+
+	// dateCalendar is a calendar excluding the listed dates.
+	dateCalendar map[string]struct{}
+
+	// weekdayCalendar is a calendar excluding the listed weekdays.
+	weekdayCalendar [7]bool
+
+	// calendarOption is the option to exclude calendar days from a schedule.
+	// The days are evaluated in the time zone of the schedule.
+	calendarOption struct {
+		calendars []Calendar
+		shift     bool
+	}
+
+	// calendarSchedule is a schedule which skips or shifts the fire times on excluded days.
+	calendarSchedule struct {
+		cron.Schedule
+		calendars []Calendar
+		location  *time.Location
+		shift     bool
+	}
+)
+
+// Excludes reports whether the day of the given time is excluded.
+func (f CalendarFunc) Excludes(t time.Time) bool { return f(t) }
+
+// Excludes reports whether the day of the given time is excluded.
+func (c dateCalendar) Excludes(t time.Time) bool {
+	_, ok := c[t.Format(dateLayout)]
+	return ok
+}
+
+// Excludes reports whether the day of the given time is excluded.
+func (c weekdayCalendar) Excludes(t time.Time) bool { return c[t.Weekday()] }
+
+// ExcludeDates returns a calendar excluding the given dates, e.g. holidays.
+// Only the year, month and day of the dates are used, regardless of their location.
+func ExcludeDates(dates ...time.Time) Calendar {
+	c := make(dateCalendar, len(dates))
+	for _, d := range dates {
+		c[d.Format(dateLayout)] = struct{}{}
+	}
+	return c
+}
+
+// ExcludeWeekdays returns a calendar excluding the given weekdays.
+func ExcludeWeekdays(days ...time.Weekday) Calendar {
+	var c weekdayCalendar
+	for _, d := range days {
+		if d >= time.Sunday && d <= time.Saturday {
+			c[d] = true
+		}
+	}
+	return c
+}
+
+// ExcludeWeekends returns a calendar excluding Saturdays and Sundays.
+func ExcludeWeekends() Calendar {
+	return ExcludeWeekdays(time.Saturday, time.Sunday)
+}
+
+// LoadICalendarFile returns a calendar excluding the days of the events of the iCalendar (.ics) file,
+// e.g. a public holidays calendar. See ParseICalendar for details.
+func LoadICalendarFile(path string) (Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidCalendar, err)
+	}
+	defer f.Close()
+
+	return ParseICalendar(f)
+}
+
+// ParseICalendar returns a calendar excluding the days of the events of the iCalendar data.
+// Every day from the start to the end of an event is excluded.
+// Recurrence rules are not expanded, only the first occurrence of a recurring event is excluded.
+func ParseICalendar(r io.Reader) (Calendar, error) {
+	lines, err := unfoldICalendarLines(r)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidCalendar, err)
+	}
+
+	c := make(dateCalendar)
+	var start, end string
+	inEvent := false
+	for i, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent, start, end = true, "", ""
+		case name == "END" && value == "VEVENT":
+			if !inEvent || start == "" {
+				return nil, errors.Join(ErrInvalidCalendar, fmt.Errorf("line %d: event without start date", i+1))
+			}
+			if err := c.addEvent(start, end); err != nil {
+				return nil, errors.Join(ErrInvalidCalendar, fmt.Errorf("line %d: %w", i+1, err))
+			}
+			inEvent = false
+		case inEvent && name == "DTSTART":
+			start = value
+		case inEvent && name == "DTEND":
+			end = value
+		}
+	}
+
+	return c, nil
+}
+
+// addEvent excludes the days of the event with the given iCalendar start and end values.
+// The end of an all-day event is exclusive, the end of a timed event is inclusive unless it's a midnight.
+func (c dateCalendar) addEvent(start, end string) error {
+	first, _, err := parseICalendarDate(start)
+	if err != nil {
+		return err
+	}
+	last := first
+	if end != "" {
+		endDate, midnight, err := parseICalendarDate(end)
+		if err != nil {
+			return err
+		}
+		last = endDate
+		if midnight && endDate.After(first) {
+			last = endDate.AddDate(0, 0, -1)
+		}
+	}
+
+	for d, n := first, 0; !d.After(last) && n < maxExcludedDays; d, n = d.AddDate(0, 0, 1), n+1 {
+		c[d.Format(dateLayout)] = struct{}{}
+	}
+	return nil
+}
+
+// parseICalendarDate parses the date of the iCalendar DATE or DATE-TIME value.
+// It also reports whether the value is a midnight, which includes all-day DATE values.
+func parseICalendarDate(value string) (time.Time, bool, error) {
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	d, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	clock := strings.TrimSuffix(strings.TrimPrefix(value[8:], "T"), "Z")
+	return d, clock == "" || strings.Trim(clock, "0") == "", nil
+}
+
+// unfoldICalendarLines returns the content lines of the iCalendar data,
+// joining the lines folded with a leading space or tab.
+func unfoldICalendarLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// String returns the string representation of the option.
+func (o calendarOption) String() string {
+	return fmt.Sprintf("Calendar(calendars=%d, shift=%t)", len(o.calendars), o.shift)
+}
+
+// Type returns the type of the option.
+func (o calendarOption) Type() asynq.OptionType { return calendarOpt }
+
+// Value returns the value of the option.
+func (o calendarOption) Value() any { return o.calendars }
+
+// SkipExcluded suppresses the fires of a schedule on the days excluded by any of the calendars,
+// e.g. asyncer.SkipExcluded(asyncer.ExcludeWeekends(), holidays).
+func SkipExcluded(calendars ...Calendar) TaskOption {
+	if len(calendars) == 0 {
+		return invalid(nil, "calendars must not be empty")
+	}
+	return calendarOption{calendars: calendars}
+}
+
+// ShiftExcluded moves the fires of a schedule on the days excluded by any of the calendars
+// to the same time of the next day which is not excluded, merging it with a regular fire at that time.
+func ShiftExcluded(calendars ...Calendar) TaskOption {
+	if len(calendars) == 0 {
+		return invalid(nil, "calendars must not be empty")
+	}
+	return calendarOption{calendars: calendars, shift: true}
+}
+
+// withCalendars returns the schedule which skips or shifts the fire times on the days
+// excluded by the calendars of the SkipExcluded and ShiftExcluded options.
+func withCalendars(schedule cron.Schedule, loc *time.Location, opts []asynq.Option) cron.Schedule {
+	opt, ok := findOption[calendarOption](opts)
+	if !ok {
+		return schedule
+	}
+	return &calendarSchedule{Schedule: schedule, calendars: opt.calendars, location: loc, shift: opt.shift}
+}
+
+// Next returns the next fire time after the given time, which is not on an excluded day.
+// It returns the zero time if there is no such fire time within a year.
+func (s *calendarSchedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t)
+	for n := 0; !next.IsZero() && s.excludes(next); n++ {
+		if n >= maxExcludedDays {
+			return time.Time{}
+		}
+		day := next.In(s.location)
+		if s.shift {
+			next = day.AddDate(0, 0, 1).In(t.Location())
+			continue
+		}
+		// Look for the first fire time of the next day.
+		y, m, d := day.Date()
+		next = s.Schedule.Next(time.Date(y, m, d+1, 0, 0, 0, 0, s.location).Add(-time.Nanosecond).In(t.Location()))
+	}
+	return next
+}
+
+// excludes reports whether the day of the time is excluded by any of the calendars.
+func (s *calendarSchedule) excludes(t time.Time) bool {
+	t = t.In(s.location)
+	for _, c := range s.calendars {
+		if c.Excludes(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package asyncer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestCalendars(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		day      time.Time
+		want     bool
+	}{
+		{name: "excluded date", calendar: ExcludeDates(time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)), day: time.Date(2026, 12, 25, 9, 0, 0, 0, time.UTC), want: true},
+		{name: "other date", calendar: ExcludeDates(time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)), day: time.Date(2026, 12, 26, 9, 0, 0, 0, time.UTC)},
+		{name: "date in another location", calendar: ExcludeDates(time.Date(2026, 12, 25, 0, 0, 0, 0, berlin)), day: time.Date(2026, 12, 25, 23, 0, 0, 0, time.UTC), want: true},
+		{name: "saturday", calendar: ExcludeWeekends(), day: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), want: true},
+		{name: "sunday", calendar: ExcludeWeekends(), day: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC), want: true},
+		{name: "monday", calendar: ExcludeWeekends(), day: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{name: "excluded weekday", calendar: ExcludeWeekdays(time.Wednesday), day: time.Date(2026, 10, 21, 9, 0, 0, 0, time.UTC), want: true},
+		{name: "unknown weekday", calendar: ExcludeWeekdays(time.Weekday(7)), day: time.Date(2026, 10, 21, 9, 0, 0, 0, time.UTC)},
+		{name: "func", calendar: CalendarFunc(func(t time.Time) bool { return t.Day() == 1 }), day: time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.calendar.Excludes(tt.day); got != tt.want {
+				t.Errorf("Excludes(%v) = %v, want %v", tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICalendar(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "all-day event",
+			data: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261225\r\nDTEND;VALUE=DATE:20261227\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []string{"2026-12-25", "2026-12-26"},
+		},
+		{
+			name: "all-day event without end",
+			data: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20260101\nEND:VEVENT\n",
+			want: []string{"2026-01-01"},
+		},
+		{
+			name: "timed event",
+			data: "BEGIN:VEVENT\nDTSTART:20261231T220000Z\nDTEND:20270101T020000Z\nEND:VEVENT\n",
+			want: []string{"2026-12-31", "2027-01-01"},
+		},
+		{
+			name: "timed event ending at midnight",
+			data: "BEGIN:VEVENT\nDTSTART:20261231T090000\nDTEND:20270101T000000\nEND:VEVENT\n",
+			want: []string{"2026-12-31"},
+		},
+		{
+			name: "folded lines",
+			data: "BEGIN:VEVENT\nSUMMARY:Christmas\n  Day\nDTSTART;VALUE=DATE:2026\n 1225\nEND:VEVENT\n",
+			want: []string{"2026-12-25"},
+		},
+		{
+			name: "several events",
+			data: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20260101\nEND:VEVENT\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20260501\nEND:VEVENT\n",
+			want: []string{"2026-01-01", "2026-05-01"},
+		},
+		{
+			name:    "event without start",
+			data:    "BEGIN:VEVENT\nSUMMARY:Holiday\nEND:VEVENT\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			data:    "BEGIN:VEVENT\nDTSTART;VALUE=DATE:2026-12-25\nEND:VEVENT\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseICalendar(strings.NewReader(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCalendar) {
+					t.Fatalf("ParseICalendar() error = %v, want %v", err, ErrInvalidCalendar)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseICalendar() error = %v", err)
+			}
+
+			dates := c.(dateCalendar)
+			if len(dates) != len(tt.want) {
+				t.Errorf("ParseICalendar() excludes %d days, want %d", len(dates), len(tt.want))
+			}
+			for _, day := range tt.want {
+				if _, ok := dates[day]; !ok {
+					t.Errorf("ParseICalendar() doesn't exclude %s", day)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadICalendarFile(t *testing.T) {
+	if _, err := LoadICalendarFile("testdata/missing.ics"); !errors.Is(err, ErrInvalidCalendar) {
+		t.Errorf("LoadICalendarFile() error = %v, want %v", err, ErrInvalidCalendar)
+	}
+}
+
+func TestCalendarSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daily, err := parseCronSpec("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	christmas := ExcludeDates(time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		opt      TaskOption
+		from     time.Time
+		want     []time.Time
+	}{
+		{
+			name:     "skip weekend",
+			location: time.UTC,
+			opt:      SkipExcluded(ExcludeWeekends()),
+			from:     time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "skip weekend and holiday",
+			location: time.UTC,
+			opt:      SkipExcluded(ExcludeWeekends(), christmas),
+			from:     time.Date(2026, 12, 24, 10, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 12, 28, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "shift weekend merges with monday",
+			location: time.UTC,
+			opt:      ShiftExcluded(ExcludeWeekends()),
+			from:     time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "shift holiday",
+			location: time.UTC,
+			opt:      ShiftExcluded(christmas),
+			from:     time.Date(2026, 12, 24, 10, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 12, 26, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 12, 27, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "days in the schedule time zone",
+			location: berlin,
+			opt:      SkipExcluded(ExcludeWeekends()),
+			from:     time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC), // Saturday 01:30 in Berlin
+			want: []time.Time{
+				time.Date(2026, 10, 19, 9, 0, 0, 0, berlin),
+			},
+		},
+		{
+			name:     "every day excluded",
+			location: time.UTC,
+			opt:      SkipExcluded(CalendarFunc(func(time.Time) bool { return true })),
+			from:     time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+			want:     []time.Time{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := daily
+			if tt.location != time.UTC {
+				if schedule, err = parseCronSpec("CRON_TZ=" + tt.location.String() + " 0 9 * * *"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s := withCalendars(schedule, tt.location, []asynq.Option{tt.opt})
+
+			next := tt.from
+			for _, want := range tt.want {
+				next = s.Next(next)
+				if !next.Equal(want) {
+					t.Fatalf("Next() = %v, want %v", next, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithCalendarsWithoutOption(t *testing.T) {
+	daily, err := parseCronSpec("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := withCalendars(daily, time.UTC, nil); s != daily {
+		t.Errorf("withCalendars() = %v, want the schedule unchanged", s)
+	}
+}
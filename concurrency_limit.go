@@ -0,0 +1,122 @@
+package asyncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// concurrencyLimitOption is a handler option that limits
+	// the number of concurrently processed tasks across all queue servers.
+	// It is handled by the queue server and ignored by asynq.
+	concurrencyLimitOption struct {
+		limit int
+	}
+)
+
+// defaultSemaphoreLease is the lease of a semaphore slot for tasks without a deadline.
+const defaultSemaphoreLease = 30 * time.Minute
+
+// acquireSemaphoreScript acquires a slot of the redis semaphore.
+// Expired slots (e.g. held by crashed workers) are released before counting.
+//
+// KEYS[1] -> semaphore key
+// ARGV[1] -> current time in milliseconds
+// ARGV[2] -> limit
+// ARGV[3] -> slot expiration time in milliseconds
+// ARGV[4] -> slot token
+//
+// Returns 1 if the slot is acquired, 0 otherwise.
+var acquireSemaphoreScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+if redis.call("ZSCORE", KEYS[1], ARGV[4]) or redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("ZADD", KEYS[1], ARGV[3], ARGV[4])
+	local ttl = redis.call("PTTL", KEYS[1])
+	if ttl < 0 or tonumber(ARGV[1]) + ttl < tonumber(ARGV[3]) then
+		redis.call("PEXPIREAT", KEYS[1], ARGV[3])
+	end
+	return 1
+end
+return 0
+`)
+
+// MaxConcurrency limits the number of tasks of the handler processed concurrently
+// across all queue servers sharing the same redis.
+// Tasks exceeding the limit are requeued with a short delay without consuming a retry while they have retries left,
+// a task with no retries left (e.g. enqueued with MaxRetry(0)) is enqueued again as a copy with a new ID instead.
+// It's a handler option, e.g.:
+//
+//	asyncer.HandlerFunc("crm:sync", syncHandler, asyncer.MaxConcurrency(5))
+func MaxConcurrency(limit int) TaskOption {
+	if limit < 1 {
+		return invalid(concurrencyLimitOption{limit: 1}, "max concurrency must be positive, got %d", limit)
+	}
+	return concurrencyLimitOption{limit: limit}
+}
+
+// String returns the string representation of the option.
+func (o concurrencyLimitOption) String() string { return fmt.Sprintf("MaxConcurrency(%d)", o.limit) }
+
+// Type returns the type of the option.
+func (o concurrencyLimitOption) Type() asynq.OptionType { return concurrencyLimitOpt }
+
+// Value returns the value of the option.
+func (o concurrencyLimitOption) Value() any { return o.limit }
+
+// concurrencyLimit returns a middleware which limits the number of concurrently processed tasks
+// sharing the given semaphore key.
+func (srv *QueueServer) concurrencyLimit(key string, limit int, next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		release, err := srv.acquireSemaphore(ctx, key, limit)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			return srv.requeue(ctx, t, defaultRequeueDelay, ErrConcurrencyLimitReached)
+		}
+		defer release()
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// acquireSemaphore acquires a slot of the redis semaphore with the given key.
+// It returns a nil release function if there is no free slot.
+// The slot is held until the task deadline at most, so it is not leaked if the worker crashes.
+func (srv *QueueServer) acquireSemaphore(ctx context.Context, key string, limit int) (func(), error) {
+	token, ok := asynq.GetTaskID(ctx)
+	if !ok {
+		return nil, ErrMissedTaskID
+	}
+
+	now := time.Now()
+	expireAt, ok := ctx.Deadline()
+	if !ok {
+		expireAt = now.Add(defaultSemaphoreLease)
+	}
+
+	acquired, err := acquireSemaphoreScript.Run(ctx, srv.redis,
+		[]string{key},
+		now.UnixMilli(), limit, expireAt.UnixMilli(), token,
+	).Int()
+	if err != nil {
+		return nil, err
+	}
+	if acquired == 0 {
+		return nil, nil
+	}
+
+	return func() {
+		// Use a fresh context: the slot must be released even if the task context is canceled.
+		_ = srv.redis.ZRem(context.Background(), key, token).Err()
+	}, nil
+}
+
+// concurrencyLimitKey returns the redis key of the task concurrency semaphore.
+func concurrencyLimitKey(taskName string) string {
+	return fmt.Sprintf("asyncer:concurrency:%s", taskName)
+}
@@ -0,0 +1,370 @@
+package asyncer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Config is the configuration of the queue server, scheduler server and enqueuer.
+	// It can be loaded from environment variables and JSON or YAML files,
+	// and converted into the option functions. E.g.:
+	//
+	//	cfg, err := asyncer.LoadConfig("asyncer.yaml", "ASYNCER_")
+	//	if err != nil {
+	//		return err
+	//	}
+	//
+	//	queueServer := asyncer.NewQueueServer(redisClient, cfg.Queue.Options()...)
+	//	enqueuer, err := asyncer.NewEnqueuer(redisClient, cfg.Enqueuer.Options()...)
+	//
+	// Zero values keep the defaults.
+	Config struct {
+		Queue     QueueServerConfig `json:"queue" yaml:"queue"`
+		Scheduler SchedulerConfig   `json:"scheduler" yaml:"scheduler"`
+		Enqueuer  EnqueuerConfig    `json:"enqueuer" yaml:"enqueuer"`
+	}
+
+	// QueueServerConfig is the configuration of the queue server.
+	QueueServerConfig struct {
+		// Concurrency is the number of workers.
+		Concurrency int `json:"concurrency" yaml:"concurrency" env:"QUEUE_CONCURRENCY"`
+		// Queues is a map of queue names to their priorities.
+		// In environment variables it's a comma-separated list of name:priority pairs, e.g. "critical:6,default:3".
+		Queues map[string]int `json:"queues" yaml:"queues" env:"QUEUE_QUEUES"`
+		// StrictPriority indicates whether the queue priority should be treated strictly.
+		StrictPriority bool `json:"strict_priority" yaml:"strict_priority" env:"QUEUE_STRICT_PRIORITY"`
+		// ShutdownTimeout is the time to wait for in-flight tasks on shutdown.
+		ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout" env:"QUEUE_SHUTDOWN_TIMEOUT"`
+		// LogLevel is the minimum log level: debug, info, warn, error or fatal.
+		LogLevel string `json:"log_level" yaml:"log_level" env:"QUEUE_LOG_LEVEL"`
+		// GroupGracePeriod is the time to wait for an incoming task before aggregating a group.
+		GroupGracePeriod Duration `json:"group_grace_period" yaml:"group_grace_period" env:"QUEUE_GROUP_GRACE_PERIOD"`
+		// GroupMaxDelay is the maximum time to wait for incoming tasks before aggregating a group.
+		GroupMaxDelay Duration `json:"group_max_delay" yaml:"group_max_delay" env:"QUEUE_GROUP_MAX_DELAY"`
+		// GroupMaxSize is the maximum number of tasks aggregated into a single task.
+		GroupMaxSize int `json:"group_max_size" yaml:"group_max_size" env:"QUEUE_GROUP_MAX_SIZE"`
+	}
+
+	// SchedulerConfig is the configuration of the scheduler server.
+	SchedulerConfig struct {
+		// Location is the time zone of the schedules, e.g. "Europe/Berlin".
+		Location string `json:"location" yaml:"location" env:"SCHEDULER_LOCATION"`
+		// LogLevel is the minimum log level: debug, info, warn, error or fatal.
+		LogLevel string `json:"log_level" yaml:"log_level" env:"SCHEDULER_LOG_LEVEL"`
+	}
+
+	// EnqueuerConfig is the configuration of the enqueuer.
+	EnqueuerConfig struct {
+		// Queue is the name of the queue the tasks are enqueued to.
+		Queue string `json:"queue" yaml:"queue" env:"ENQUEUER_QUEUE"`
+		// TaskDeadline is the time limit for the task to be processed.
+		TaskDeadline Duration `json:"task_deadline" yaml:"task_deadline" env:"ENQUEUER_TASK_DEADLINE"`
+		// MaxRetry is the number of times a failed task is retried.
+		MaxRetry *int `json:"max_retry" yaml:"max_retry" env:"ENQUEUER_MAX_RETRY"`
+		// DefaultUniqueness configures whether tasks are deduplicated by the task name and payload by default.
+		DefaultUniqueness *bool `json:"default_uniqueness" yaml:"default_uniqueness" env:"ENQUEUER_DEFAULT_UNIQUENESS"`
+		// RejectPausedQueues configures whether tasks for paused queues are rejected.
+		RejectPausedQueues bool `json:"reject_paused_queues" yaml:"reject_paused_queues" env:"ENQUEUER_REJECT_PAUSED_QUEUES"`
+	}
+
+	// Duration is a time.Duration which is represented as a string (e.g. "10s") in config files.
+	Duration time.Duration
+)
+
+// UnmarshalText parses the duration from a string, e.g. "1m30s".
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText returns the string representation of the duration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfig loads the configuration from the file and overrides it with the environment variables
+// with the given prefix. The file is skipped if the path is empty.
+// It returns an error if the configuration can't be loaded or is invalid.
+func LoadConfig(path, envPrefix string) (Config, error) {
+	return loadConfig(path, true, envPrefix)
+}
+
+// LoadConfigFromFile loads the configuration from a JSON (.json) or YAML (.yaml, .yml) file.
+// The environment variables are not read, use LoadConfig to override the file with them.
+// It returns an error if the configuration can't be loaded or is invalid.
+func LoadConfigFromFile(path string) (Config, error) {
+	return loadConfig(path, false, "")
+}
+
+// LoadConfigFromEnv loads the configuration from the environment variables with the given prefix,
+// e.g. with prefix "ASYNCER_" the queue concurrency is read from ASYNCER_QUEUE_CONCURRENCY.
+// It returns an error if the configuration can't be loaded or is invalid.
+func LoadConfigFromEnv(prefix string) (Config, error) {
+	return loadConfig("", true, prefix)
+}
+
+// loadConfig loads the configuration from the file, if the path is not empty,
+// and overrides it with the environment variables with the given prefix, if env is true.
+func loadConfig(path string, env bool, envPrefix string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, errors.Join(ErrFailedToLoadConfig, err)
+		}
+	}
+	if env {
+		if err := cfg.loadEnv(envPrefix); err != nil {
+			return Config{}, errors.Join(ErrFailedToLoadConfig, err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate returns an error describing all invalid values of the configuration.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Queue.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("queue.concurrency must not be negative, got %d", c.Queue.Concurrency))
+	}
+	for name, priority := range c.Queue.Queues {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New("queue.queues must not contain an empty queue name"))
+		}
+		if priority < 1 {
+			errs = append(errs, fmt.Errorf("queue.queues: priority of queue %q must be positive, got %d", name, priority))
+		}
+	}
+	if c.Queue.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("queue.shutdown_timeout must not be negative, got %v", time.Duration(c.Queue.ShutdownTimeout)))
+	}
+	if c.Queue.LogLevel != "" && !isValidLogLevel(c.Queue.LogLevel) {
+		errs = append(errs, fmt.Errorf("queue.log_level: unknown log level %q", c.Queue.LogLevel))
+	}
+	if c.Queue.GroupGracePeriod != 0 && time.Duration(c.Queue.GroupGracePeriod) < time.Second {
+		errs = append(errs, fmt.Errorf("queue.group_grace_period must be at least 1s, got %v", time.Duration(c.Queue.GroupGracePeriod)))
+	}
+	if c.Queue.GroupMaxDelay < 0 {
+		errs = append(errs, fmt.Errorf("queue.group_max_delay must not be negative, got %v", time.Duration(c.Queue.GroupMaxDelay)))
+	}
+	if c.Queue.GroupMaxSize < 0 {
+		errs = append(errs, fmt.Errorf("queue.group_max_size must not be negative, got %d", c.Queue.GroupMaxSize))
+	}
+
+	if c.Scheduler.Location != "" {
+		if _, err := time.LoadLocation(c.Scheduler.Location); err != nil {
+			errs = append(errs, fmt.Errorf("scheduler.location: unknown time zone %q", c.Scheduler.Location))
+		}
+	}
+	if c.Scheduler.LogLevel != "" && !isValidLogLevel(c.Scheduler.LogLevel) {
+		errs = append(errs, fmt.Errorf("scheduler.log_level: unknown log level %q", c.Scheduler.LogLevel))
+	}
+
+	if c.Enqueuer.TaskDeadline < 0 {
+		errs = append(errs, fmt.Errorf("enqueuer.task_deadline must not be negative, got %v", time.Duration(c.Enqueuer.TaskDeadline)))
+	}
+	if c.Enqueuer.MaxRetry != nil && *c.Enqueuer.MaxRetry < 0 {
+		errs = append(errs, fmt.Errorf("enqueuer.max_retry must not be negative, got %d", *c.Enqueuer.MaxRetry))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrInvalidConfig}, errs...)...)
+	}
+	return nil
+}
+
+// Options converts the configuration into the queue server options.
+func (c QueueServerConfig) Options() []QueueServerOption {
+	var opts []QueueServerOption
+	if c.Concurrency > 0 {
+		opts = append(opts, WithQueueConcurrency(c.Concurrency))
+	}
+	if len(c.Queues) > 0 {
+		opts = append(opts, WithQueues(c.Queues))
+	}
+	if c.StrictPriority {
+		opts = append(opts, WithQueueStrictPriority(true))
+	}
+	if c.ShutdownTimeout > 0 {
+		opts = append(opts, WithQueueShutdownTimeout(time.Duration(c.ShutdownTimeout)))
+	}
+	if c.LogLevel != "" {
+		opts = append(opts, WithQueueLogLevel(c.LogLevel))
+	}
+	if c.GroupGracePeriod > 0 {
+		opts = append(opts, WithQueueGroupGracePeriod(time.Duration(c.GroupGracePeriod)))
+	}
+	if c.GroupMaxDelay > 0 {
+		opts = append(opts, WithQueueGroupMaxDelay(time.Duration(c.GroupMaxDelay)))
+	}
+	if c.GroupMaxSize > 0 {
+		opts = append(opts, WithQueueGroupMaxSize(c.GroupMaxSize))
+	}
+	return opts
+}
+
+// Options converts the configuration into the scheduler server options.
+func (c SchedulerConfig) Options() []SchedulerServerOption {
+	var opts []SchedulerServerOption
+	if c.Location != "" {
+		opts = append(opts, WithSchedulerLocation(c.Location))
+	}
+	if c.LogLevel != "" {
+		opts = append(opts, WithSchedulerLogLevel(c.LogLevel))
+	}
+	return opts
+}
+
+// Options converts the configuration into the enqueuer options.
+func (c EnqueuerConfig) Options() []EnqueuerOption {
+	var opts []EnqueuerOption
+	if c.Queue != "" {
+		opts = append(opts, WithQueueNameEnq(c.Queue))
+	}
+	if c.TaskDeadline > 0 {
+		opts = append(opts, WithTaskDeadline(time.Duration(c.TaskDeadline)))
+	}
+	if c.MaxRetry != nil {
+		opts = append(opts, WithMaxRetry(*c.MaxRetry))
+	}
+	if c.DefaultUniqueness != nil {
+		opts = append(opts, WithDefaultUniqueness(*c.DefaultUniqueness))
+	}
+	if c.RejectPausedQueues {
+		opts = append(opts, WithRejectPausedQueues(true))
+	}
+	return opts
+}
+
+// loadFile loads the configuration from a JSON or YAML file, depending on the file extension.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return json.Unmarshal(data, c)
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedConfigFormat, ext)
+	}
+}
+
+// loadEnv overrides the configuration with the environment variables with the given prefix.
+// Every field with the `env` tag is read from the variable named by the prefix and the tag value.
+func (c *Config) loadEnv(prefix string) error {
+	var errs []error
+	for _, section := range []reflect.Value{
+		reflect.ValueOf(&c.Queue).Elem(),
+		reflect.ValueOf(&c.Scheduler).Elem(),
+		reflect.ValueOf(&c.Enqueuer).Elem(),
+	} {
+		for i := range section.NumField() {
+			tag := section.Type().Field(i).Tag.Get("env")
+			if tag == "" {
+				continue
+			}
+			value, ok := os.LookupEnv(prefix + tag)
+			if !ok {
+				continue
+			}
+			if err := setFromEnv(section.Field(i), value); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", prefix, tag, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setFromEnv parses the environment variable value into the field.
+func setFromEnv(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch field.Interface().(type) {
+	case Duration:
+		var d Duration
+		if err := d.UnmarshalText([]byte(value)); err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+	case string:
+		field.SetString(value)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&n))
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&b))
+	case map[string]int:
+		queues, err := parseQueuesEnv(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(queues))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// parseQueuesEnv parses a comma-separated list of name:priority pairs, e.g. "critical:6,default:3".
+// A queue without a priority gets the default priority.
+func parseQueuesEnv(value string) (map[string]int, error) {
+	queues := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, priority, found := strings.Cut(pair, ":")
+		if !found {
+			queues[name] = defaultQueuePriority
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(priority))
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority of queue %q: %w", name, err)
+		}
+		queues[strings.TrimSpace(name)] = n
+	}
+	return queues, nil
+}
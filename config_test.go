@@ -0,0 +1,154 @@
+package asyncer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	const yamlConfig = `
+queue:
+  concurrency: 4
+  queues:
+    critical: 6
+    default: 3
+  shutdown_timeout: 30s
+  group_grace_period: 2s
+scheduler:
+  location: Europe/Berlin
+enqueuer:
+  task_deadline: 1m30s
+  max_retry: 0
+`
+	maxRetry := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		file    string // file name, the file is not loaded if empty
+		data    string
+		env     map[string]string
+		load    func(path string) (Config, error)
+		want    Config
+		wantErr error
+	}{
+		{
+			name: "file only",
+			file: "asyncer.yaml",
+			data: yamlConfig,
+			// Unprefixed variables must not affect the file configuration.
+			env:  map[string]string{"QUEUE_CONCURRENCY": "99"},
+			load: LoadConfigFromFile,
+			want: Config{
+				Queue: QueueServerConfig{
+					Concurrency:      4,
+					Queues:           map[string]int{"critical": 6, "default": 3},
+					ShutdownTimeout:  Duration(30 * time.Second),
+					GroupGracePeriod: Duration(2 * time.Second),
+				},
+				Scheduler: SchedulerConfig{Location: "Europe/Berlin"},
+				Enqueuer:  EnqueuerConfig{TaskDeadline: Duration(90 * time.Second), MaxRetry: maxRetry(0)},
+			},
+		},
+		{
+			name: "json file only",
+			file: "asyncer.json",
+			data: `{"queue":{"concurrency":2,"shutdown_timeout":"5s"}}`,
+			load: LoadConfigFromFile,
+			want: Config{Queue: QueueServerConfig{Concurrency: 2, ShutdownTimeout: Duration(5 * time.Second)}},
+		},
+		{
+			name: "env only",
+			env: map[string]string{
+				"TEST_QUEUE_CONCURRENCY":      "8",
+				"TEST_QUEUE_QUEUES":           "critical:6, default",
+				"TEST_ENQUEUER_TASK_DEADLINE": "10s",
+				"QUEUE_CONCURRENCY":           "99",
+			},
+			load: func(string) (Config, error) { return LoadConfigFromEnv("TEST_") },
+			want: Config{
+				Queue:    QueueServerConfig{Concurrency: 8, Queues: map[string]int{"critical": 6, "default": 1}},
+				Enqueuer: EnqueuerConfig{TaskDeadline: Duration(10 * time.Second)},
+			},
+		},
+		{
+			name: "env overrides file",
+			file: "asyncer.yml",
+			data: yamlConfig,
+			env: map[string]string{
+				"TEST_QUEUE_CONCURRENCY":  "8",
+				"TEST_ENQUEUER_MAX_RETRY": "5",
+			},
+			load: func(path string) (Config, error) { return LoadConfig(path, "TEST_") },
+			want: Config{
+				Queue: QueueServerConfig{
+					Concurrency:      8,
+					Queues:           map[string]int{"critical": 6, "default": 3},
+					ShutdownTimeout:  Duration(30 * time.Second),
+					GroupGracePeriod: Duration(2 * time.Second),
+				},
+				Scheduler: SchedulerConfig{Location: "Europe/Berlin"},
+				Enqueuer:  EnqueuerConfig{TaskDeadline: Duration(90 * time.Second), MaxRetry: maxRetry(5)},
+			},
+		},
+		{
+			name:    "invalid yaml duration",
+			file:    "asyncer.yaml",
+			data:    "queue:\n  shutdown_timeout: 30\n",
+			load:    LoadConfigFromFile,
+			wantErr: ErrFailedToLoadConfig,
+		},
+		{
+			name:    "invalid env value",
+			env:     map[string]string{"TEST_QUEUE_CONCURRENCY": "many"},
+			load:    func(string) (Config, error) { return LoadConfigFromEnv("TEST_") },
+			wantErr: ErrFailedToLoadConfig,
+		},
+		{
+			name:    "invalid value",
+			file:    "asyncer.yaml",
+			data:    "scheduler:\n  location: Mars/Olympus\n",
+			load:    LoadConfigFromFile,
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "unsupported format",
+			file:    "asyncer.toml",
+			data:    "",
+			load:    LoadConfigFromFile,
+			wantErr: ErrUnsupportedConfigFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			var path string
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), tt.file)
+				if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := tt.load(path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package asyncer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type (
+	// ScheduleInfo describes a schedule registered in the scheduler server.
+	ScheduleInfo struct {
+		// EntryID is the scheduler entry ID of the schedule.
+		EntryID string
+		// ScheduleID is the stable identifier of the schedule, used to query its history (see ScheduleHistory).
+		ScheduleID string
+		// TaskName is the name of the scheduled task.
+		TaskName string
+		// CronSpec is the cron spec of the schedule.
+		CronSpec string
+		// Location is the time zone the cron spec is evaluated in.
+		Location *time.Location
+		// NextRuns are the next fire times of the schedule in its time zone.
+		NextRuns []time.Time
+	}
+)
+
+// ValidateCronSpec returns an error wrapping ErrInvalidCronSpec if the cron spec can't be parsed.
+// It accepts the same syntax as the scheduler: five fields, descriptors like "@daily" or "@every 1h",
+// one-off times like "@at 2026-12-01 09:00 Europe/Berlin", recurrence rules like
+// "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;BYHOUR=9", and an optional CRON_TZ= prefix.
+// For more information about cron spec, see https://pkg.go.dev/github.com/robfig/cron/v3#hdr-CRON_Expression_Format.
+func ValidateCronSpec(cronSpec string) error {
+	_, err := parseCronSpec(cronSpec)
+	return err
+}
+
+// NextRuns returns the next n fire times of the cron spec after the given time.
+// Cron specs without a time zone prefix are evaluated in the location of the given time.
+func NextRuns(cronSpec string, from time.Time, n int) ([]time.Time, error) {
+	schedule, err := parseCronSpec(cronSpec)
+	if err != nil {
+		return nil, err
+	}
+	return nextRuns(schedule, from, n), nil
+}
+
+// Schedules returns the schedules registered in the scheduler server ordered by the next fire time,
+// each with its next n fire times in its time zone.
+// It's useful for admin pages and startup logs.
+func (srv *SchedulerServer) Schedules(n int) []ScheduleInfo {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	infos := make([]ScheduleInfo, 0, len(srv.schedules))
+	for entryID, s := range srv.schedules {
+		infos = append(infos, ScheduleInfo{
+			EntryID:    entryID,
+			ScheduleID: s.id,
+			TaskName:   s.taskName,
+			CronSpec:   s.cronSpec,
+			Location:   s.location,
+			NextRuns:   nextRuns(s.schedule, time.Now().In(s.location), n),
+		})
+	}
+
+	slices.SortFunc(infos, func(a, b ScheduleInfo) int {
+		if len(a.NextRuns) > 0 && len(b.NextRuns) > 0 {
+			if c := a.NextRuns[0].Compare(b.NextRuns[0]); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.TaskName, b.TaskName)
+	})
+
+	return infos
+}
+
+// parseCronSpec parses the cron spec the same way as the scheduler does,
+// including the extended syntax (see parseExtendedSpec).
+func parseCronSpec(cronSpec string) (cron.Schedule, error) {
+	if cronSpec == "" {
+		return nil, errors.Join(ErrInvalidCronSpec, ErrCronSpecIsEmpty)
+	}
+	schedule, ok, err := parseExtendedSpec(cronSpec)
+	if !ok {
+		schedule, err = cron.ParseStandard(cronSpec)
+	}
+	if err != nil {
+		return nil, errors.Join(ErrInvalidCronSpec, fmt.Errorf("%q: %w", cronSpec, err))
+	}
+	return schedule, nil
+}
+
+// nextRuns returns the next n fire times of the schedule after the given time.
+func nextRuns(schedule cron.Schedule, from time.Time, n int) []time.Time {
+	runs := make([]time.Time, 0, max(n, 0))
+	for t := from; len(runs) < n; {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
@@ -0,0 +1,73 @@
+package asyncer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// EnqueueError is returned when a task fails to enqueue.
+// It carries the task name and wraps ErrFailedToEnqueueTask along with the cause,
+// so the outcome can be checked with errors.Is and errors.As. E.g.:
+//
+//	err := enqueuer.EnqueueTask(ctx, "email:welcome", payload)
+//	switch {
+//	case errors.Is(err, asyncer.ErrDuplicateTask), errors.Is(err, asyncer.ErrTaskIDConflict):
+//		// 409 Conflict
+//	case errors.Is(err, asyncer.ErrRedisUnavailable), errors.Is(err, asyncer.ErrQueuePaused):
+//		// 503 Service Unavailable
+//	case errors.Is(err, asyncer.ErrFailedToMarshalPayload):
+//		// 400 Bad Request
+//	}
+type EnqueueError struct {
+	// TaskName is the name of the task that failed to enqueue.
+	TaskName string
+	// Err is the cause of the failure.
+	Err error
+}
+
+// Error returns the error message.
+func (e *EnqueueError) Error() string {
+	return fmt.Sprintf("%s %q: %v", ErrFailedToEnqueueTask, e.TaskName, e.Err)
+}
+
+// Unwrap returns ErrFailedToEnqueueTask and the cause of the failure.
+func (e *EnqueueError) Unwrap() []error {
+	return []error{ErrFailedToEnqueueTask, e.Err}
+}
+
+// newEnqueueError creates a new EnqueueError for the given task name.
+// Known asynq and redis errors are wrapped with the corresponding asyncer errors.
+// An error which is already an *EnqueueError is returned as is.
+func newEnqueueError(taskName string, err error) error {
+	var eerr *EnqueueError
+	if errors.As(err, &eerr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, ErrDuplicateTask):
+		// Already classified, e.g. by the uniqueness lock.
+	case errors.Is(err, asynq.ErrDuplicateTask):
+		err = errors.Join(ErrDuplicateTask, err)
+	case errors.Is(err, asynq.ErrTaskIDConflict):
+		err = errors.Join(ErrTaskIDConflict, err)
+	case isRedisUnavailableErr(err):
+		err = errors.Join(ErrRedisUnavailable, err)
+	}
+
+	return &EnqueueError{TaskName: taskName, Err: err}
+}
+
+// isRedisUnavailableErr reports whether the error is caused by a redis connection failure.
+func isRedisUnavailableErr(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, redis.ErrClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -4,21 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
 )
 
+// Default enqueuer options.
+const (
+	defaultTaskDeadline = time.Minute // Default task deadline
+	defaultMaxRetry     = 3           // Default max retry
+)
+
 type (
 	// Enqueuer is a helper struct for enqueuing tasks.
 	// You can encapsulate this struct in your own struct to add queue methods.
 	// See pkg/worker/_example/enqueuer.go for an example.
 	Enqueuer struct {
 		client       *asynq.Client
+		redis        redis.UniversalClient
+		inspector    *asynq.Inspector
 		queueName    string
 		taskDeadline time.Duration
 		maxRetry     int
+		unique       bool
+		rejectPaused bool
+		strict       bool
 	}
 
 	// EnqueuerOption is a function that configures an enqueuer.
@@ -33,6 +45,7 @@ type (
 //   - queue name: "default"
 //   - task deadline: 1 minute
 //   - max retry: 3
+//   - default uniqueness: enabled
 func NewEnqueuerWithAsynqClient(client *asynq.Client, opt ...EnqueuerOption) (*Enqueuer, error) {
 	if client == nil {
 		return nil, ErrMissedAsynqClient
@@ -40,15 +53,27 @@ func NewEnqueuerWithAsynqClient(client *asynq.Client, opt ...EnqueuerOption) (*E
 
 	e := &Enqueuer{
 		client:       client,
-		queueName:    queueName,
-		taskDeadline: time.Minute,
-		maxRetry:     3,
+		queueName:    defaultQueueName,
+		taskDeadline: defaultTaskDeadline,
+		maxRetry:     defaultMaxRetry,
+		unique:       true,
 	}
 
 	for _, o := range opt {
 		o(e)
 	}
 
+	if e.strict {
+		if err := e.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The inspector is used to manage already enqueued tasks (e.g. debounced ones).
+	if e.redis != nil {
+		e.inspector = asynq.NewInspectorFromRedisClient(e.redis)
+	}
+
 	return e, nil
 }
 
@@ -72,7 +97,7 @@ func NewEnqueuer(redisClient redis.UniversalClient, opt ...EnqueuerOption) (*Enq
 		return nil, errors.Join(ErrFailedToCreateEnqueuerWithClient, err)
 	}
 
-	return NewEnqueuerWithAsynqClient(client, opt...)
+	return NewEnqueuerWithAsynqClient(client, append([]EnqueuerOption{WithRedisClientEnq(redisClient)}, opt...)...)
 }
 
 // MustNewEnqueuer creates a new Enqueuer with the given Redis connection string and options.
@@ -89,12 +114,16 @@ func MustNewEnqueuer(redisClient redis.UniversalClient, opt ...EnqueuerOption) *
 // EnqueueTask enqueues a task to be processed asynchronously.
 // It takes a context and a task as parameters.
 // The task is enqueued with the specified queue name, deadline, maximum retry count, and uniqueness constraint.
-// Returns an error if the task fails to enqueue.
+// If the payload provides a uniqueness key (see UniqueKeyer) or the UniqueKey option is set,
+// the task is deduplicated by the task name and the key instead of the entire payload:
+// until the task succeeds or the key TTL expires, or without the redis client (see WithRedisClientEnq),
+// while the task exists in the queue, including the archived and retained tasks.
+// Returns an *EnqueueError if the task fails to enqueue, it wraps ErrDuplicateTask if the task is a duplicate.
+// In strict mode (see WithStrictOptionsEnq), it wraps ErrInvalidTaskOption if any task option is invalid.
 func (e *Enqueuer) EnqueueTask(ctx context.Context, taskName string, payload any, opts ...TaskOption) error {
-	// Marshal payload to JSON bytes
-	jsonPayload, err := json.Marshal(payload)
+	opts, err := normalizeOptions(opts, e.strict)
 	if err != nil {
-		return errors.Join(ErrFailedToEnqueueTask, err)
+		return newEnqueueError(taskName, err)
 	}
 
 	// Set default options for enqueuing task.
@@ -103,15 +132,100 @@ func (e *Enqueuer) EnqueueTask(ctx context.Context, taskName string, payload any
 		asynq.Queue(e.queueName),
 		asynq.Deadline(time.Now().Add(e.taskDeadline)),
 		asynq.MaxRetry(e.maxRetry),
-		asynq.Unique(e.taskDeadline),
+	}
+
+	// Deduplicate by the task name and payload, unless there is a custom uniqueness key.
+	key, ttl := e.uniqueKey(payload, opts)
+	if key == "" {
+		if e.unique {
+			defaultOptions = append(defaultOptions, asynq.Unique(e.taskDeadline))
+		}
+		return e.enqueue(ctx, taskName, payload, append(defaultOptions, opts...))
+	}
+
+	queue := queueFromOptions(e.queueName, opts)
+	if e.redis == nil {
+		// Without redis, the task ID derived from the key deduplicates the tasks while the task exists in the queue.
+		defaultOptions = append(defaultOptions, asynq.TaskID(uniqueTaskIDWithoutLock(taskName, key)))
+		err := e.enqueue(ctx, taskName, payload, append(defaultOptions, opts...))
+		var eerr *EnqueueError
+		if errors.As(err, &eerr) && errors.Is(eerr.Err, asynq.ErrTaskIDConflict) {
+			eerr.Err = errors.Join(ErrDuplicateTask, eerr.Err)
+		}
+		return err
+	}
+
+	// The lock is released by the queue server once the task succeeds (see releaseUniqueLock),
+	// it finds the lock by the task ID unless the TaskID option overrides it.
+	lockKey := uniqueLockKey(queue, taskName, key)
+	defaultOptions = append(defaultOptions, asynq.TaskID(uniqueTaskID(lockKey)))
+	opts = append(defaultOptions, opts...)
+
+	release, err := e.acquireUniqueLock(ctx, lockKey, taskIDFromOptions(opts), ttl)
+	if err != nil {
+		return newEnqueueError(taskName, err)
+	}
+	if err := e.enqueue(ctx, taskName, payload, opts); err != nil {
+		release()
+		return err
+	}
+
+	return nil
+}
+
+// validate returns an error describing every invalid enqueuer option.
+func (e *Enqueuer) validate() error {
+	var errs []error
+	if e.queueName == "" {
+		errs = append(errs, errors.New("queue name must not be empty"))
+	}
+	if e.taskDeadline <= 0 {
+		errs = append(errs, fmt.Errorf("task deadline must be positive, got %v", e.taskDeadline))
+	}
+	if e.maxRetry < 0 {
+		errs = append(errs, fmt.Errorf("max retry must not be negative, got %d", e.maxRetry))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrInvalidEnqueuerOption}, errs...)...)
+	}
+	return nil
+}
+
+// uniqueKey returns the custom uniqueness key of the task and its TTL.
+// The key set by the UniqueKey option takes precedence over the key provided by the payload.
+func (e *Enqueuer) uniqueKey(payload any, opts []asynq.Option) (string, time.Duration) {
+	if opt, ok := findOption[uniqueKeyOption](opts); ok && opt.key != "" {
+		return opt.key, opt.ttl
+	}
+	return uniqueKeyFromPayload(payload), e.taskDeadline
+}
+
+// enqueue marshals the payload to JSON and enqueues the task with the given options.
+// If the enqueuer rejects tasks for paused queues, it returns an error wrapping ErrQueuePaused.
+func (e *Enqueuer) enqueue(ctx context.Context, taskName string, payload any, opts []asynq.Option) error {
+	if e.rejectPaused {
+		if e.redis == nil {
+			return newEnqueueError(taskName, ErrMissedRedisClient)
+		}
+		paused, err := isQueuePaused(ctx, e.redis, queueFromOptions(e.queueName, opts))
+		if err != nil {
+			return newEnqueueError(taskName, err)
+		}
+		if paused {
+			return newEnqueueError(taskName, ErrQueuePaused)
+		}
+	}
+
+	// Marshal payload to JSON bytes
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return newEnqueueError(taskName, errors.Join(ErrFailedToMarshalPayload, err))
 	}
 
 	// Enqueue task
-	if _, err := e.client.Enqueue(
-		asynq.NewTask(taskName, jsonPayload),
-		append(defaultOptions, opts...)...,
-	); err != nil {
-		return errors.Join(ErrFailedToEnqueueTask, err)
+	if _, err := e.client.EnqueueContext(ctx, asynq.NewTask(taskName, jsonPayload), opts...); err != nil {
+		return newEnqueueError(taskName, err)
 	}
 
 	return nil
@@ -120,7 +234,11 @@ func (e *Enqueuer) EnqueueTask(ctx context.Context, taskName string, payload any
 // Close closes the Enqueuer and releases any resources associated with it.
 // It returns an error if there was a problem closing the Enqueuer.
 func (e *Enqueuer) Close() error {
-	if err := e.client.Close(); err != nil {
+	err := e.client.Close()
+	if e.inspector != nil {
+		err = errors.Join(err, e.inspector.Close())
+	}
+	if err != nil {
 		return errors.Join(ErrFailedToCloseEnqueuer, err)
 	}
 
@@ -150,3 +268,43 @@ func WithMaxRetry(n int) EnqueuerOption {
 		e.maxRetry = n
 	}
 }
+
+// WithRedisClientEnq configures the redis client used by the enqueuer to keep its own state,
+// e.g. for debounced and throttled tasks.
+// It's set automatically when the enqueuer is created with NewEnqueuer.
+func WithRedisClientEnq(redisClient redis.UniversalClient) EnqueuerOption {
+	return func(e *Enqueuer) {
+		if redisClient != nil {
+			e.redis = redisClient
+		}
+	}
+}
+
+// WithDefaultUniqueness configures whether tasks are deduplicated by the task name and payload by default.
+// If enabled, a task is unique within the task deadline.
+// Tasks with a custom uniqueness key (see UniqueKey and UniqueKeyer) are deduplicated by the key regardless of this option.
+func WithDefaultUniqueness(enabled bool) EnqueuerOption {
+	return func(e *Enqueuer) {
+		e.unique = enabled
+	}
+}
+
+// WithRejectPausedQueues configures whether tasks for paused queues are rejected.
+// If enabled, enqueuing a task to a paused queue returns an error wrapping ErrQueuePaused.
+// Otherwise, the tasks are accepted and processed once the queue is resumed.
+func WithRejectPausedQueues(reject bool) EnqueuerOption {
+	return func(e *Enqueuer) {
+		e.rejectPaused = reject
+	}
+}
+
+// WithStrictOptionsEnq configures whether invalid options are reported as errors.
+// If enabled, the enqueuer constructors return an error wrapping ErrInvalidEnqueuerOption
+// if any enqueuer option is invalid, and enqueuing returns an error wrapping ErrInvalidTaskOption
+// if any task option is invalid, e.g. an empty task ID or a deadline which has passed.
+// Otherwise, invalid values are coerced to the closest valid ones.
+func WithStrictOptionsEnq(strict bool) EnqueuerOption {
+	return func(e *Enqueuer) {
+		e.strict = strict
+	}
+}
@@ -0,0 +1,173 @@
+package asyncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// debounceState is the state of a debounced task stored in redis.
+type debounceState struct {
+	Queue  string `json:"queue"`
+	TaskID string `json:"task_id"`
+}
+
+// EnqueueTaskDebounced enqueues a task which is processed after the given delay.
+// Every subsequent call with the same task name and key within the delay
+// replaces the pending task with a new one and resets the delay timer,
+// so only the latest payload is processed once the key gets quiet.
+// If the previous task is already being processed, it is not affected.
+// The new task is enqueued before the previous one is removed, so a failed call keeps the previous task.
+// It requires the redis client (see NewEnqueuer and WithRedisClientEnq).
+func (e *Enqueuer) EnqueueTaskDebounced(ctx context.Context, taskName, key string, delay time.Duration, payload any, opts ...TaskOption) error {
+	if e.redis == nil {
+		return newEnqueueError(taskName, ErrMissedRedisClient)
+	}
+	if key == "" {
+		return newEnqueueError(taskName, ErrTaskKeyIsEmpty)
+	}
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	opts, err := normalizeOptions(opts, e.strict)
+	if err != nil {
+		return newEnqueueError(taskName, err)
+	}
+
+	state := debounceState{
+		Queue:  queueFromOptions(e.queueName, opts),
+		TaskID: fmt.Sprintf("debounce:%s:%s:%d", taskName, key, time.Now().UnixNano()),
+	}
+	rawState, err := json.Marshal(state)
+	if err != nil {
+		return newEnqueueError(taskName, err)
+	}
+
+	// Enqueue the new task first, so the previous one is kept if the enqueue fails.
+	defaultOptions := []asynq.Option{
+		asynq.Queue(e.queueName),
+		asynq.Deadline(time.Now().Add(delay + e.taskDeadline)),
+		asynq.MaxRetry(e.maxRetry),
+	}
+	modeOptions := []asynq.Option{
+		asynq.TaskID(state.TaskID),
+		asynq.ProcessIn(delay),
+	}
+	if err := e.enqueue(ctx, taskName, payload, append(append(defaultOptions, opts...), modeOptions...)); err != nil {
+		return err
+	}
+
+	// Store the new task ID and get the previous one atomically,
+	// so of the concurrent calls only the one swapping last keeps its task.
+	prevState, err := e.redis.SetArgs(ctx, debounceKey(taskName, key), rawState, redis.SetArgs{
+		Get: true,
+		TTL: delay + e.taskDeadline,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		// The new task is not tracked, so it would not be debounced by the next call.
+		return newEnqueueError(taskName, errors.Join(err, e.deleteDebouncedTask(state)))
+	}
+
+	// Remove the previous task, if it's still waiting to be processed.
+	if prevState != "" {
+		var prev debounceState
+		if err := json.Unmarshal([]byte(prevState), &prev); err == nil && prev.TaskID != "" {
+			if err := e.deleteDebouncedTask(prev); err != nil {
+				return newEnqueueError(taskName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteDebouncedTask deletes the debounced task, unless it's already processed or being processed.
+func (e *Enqueuer) deleteDebouncedTask(state debounceState) error {
+	err := e.inspector.DeleteTask(state.Queue, state.TaskID)
+	if err == nil || errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return nil
+	}
+	// asynq refuses to delete an active task with an untyped error.
+	if info, infoErr := e.inspector.GetTaskInfo(state.Queue, state.TaskID); infoErr == nil && info.State == asynq.TaskStateActive {
+		return nil
+	}
+	return fmt.Errorf("failed to delete debounced task %q: %w", state.TaskID, err)
+}
+
+// EnqueueTaskThrottled enqueues a task only if no task with the same task name and key
+// was enqueued within the given window.
+// It returns an error that wraps ErrTaskThrottled if the task was rejected.
+// It requires the redis client (see NewEnqueuer and WithRedisClientEnq).
+func (e *Enqueuer) EnqueueTaskThrottled(ctx context.Context, taskName, key string, window time.Duration, payload any, opts ...TaskOption) error {
+	if e.redis == nil {
+		return newEnqueueError(taskName, ErrMissedRedisClient)
+	}
+	if key == "" {
+		return newEnqueueError(taskName, ErrTaskKeyIsEmpty)
+	}
+	if window <= 0 {
+		window = time.Second
+	}
+
+	opts, err := normalizeOptions(opts, e.strict)
+	if err != nil {
+		return newEnqueueError(taskName, err)
+	}
+
+	stateKey := throttleKey(taskName, key)
+	taskID := fmt.Sprintf("throttle:%s:%s:%d", taskName, key, time.Now().UnixNano())
+
+	ok, err := e.redis.SetNX(ctx, stateKey, taskID, window).Result()
+	if err != nil {
+		return newEnqueueError(taskName, err)
+	}
+	if !ok {
+		return newEnqueueError(taskName, ErrTaskThrottled)
+	}
+
+	defaultOptions := []asynq.Option{
+		asynq.Queue(e.queueName),
+		asynq.Deadline(time.Now().Add(e.taskDeadline)),
+		asynq.MaxRetry(e.maxRetry),
+	}
+
+	if err := e.enqueue(ctx, taskName, payload, append(append(defaultOptions, opts...), asynq.TaskID(taskID))); err != nil {
+		// Release the window, so the next attempt is not throttled because of the failed one.
+		if delErr := e.redis.Del(ctx, stateKey).Err(); delErr != nil {
+			return errors.Join(err, delErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// debounceKey returns the redis key of the debounced task state.
+func debounceKey(taskName, key string) string {
+	return fmt.Sprintf("asyncer:debounce:%s:%s", taskName, key)
+}
+
+// throttleKey returns the redis key of the throttled task state.
+func throttleKey(taskName, key string) string {
+	return fmt.Sprintf("asyncer:throttle:%s:%s", taskName, key)
+}
+
+// queueFromOptions returns the queue name set by the given options.
+// If there is no queue option, the default queue name is returned.
+func queueFromOptions(defaultQueue string, opts []asynq.Option) string {
+	queue := defaultQueue
+	for _, opt := range opts {
+		if opt != nil && opt.Type() == asynq.QueueOpt {
+			if name, ok := opt.Value().(string); ok {
+				queue = name
+			}
+		}
+	}
+	return queue
+}
@@ -17,4 +17,50 @@ var (
 	ErrCronSpecIsEmpty                  = errors.New("cron spec is empty")
 	ErrTaskNameIsEmpty                  = errors.New("task name is empty")
 	ErrFailedToRunSchedulerServer       = errors.New("failed to run scheduler server")
+	ErrMissedRedisClient                = errors.New("missed redis client")
+	ErrTaskKeyIsEmpty                   = errors.New("task key is empty")
+	ErrTaskThrottled                    = errors.New("task throttled")
+	ErrDuplicateTask                    = errors.New("task already exists")
+	ErrTaskIDConflict                   = errors.New("task id conflicts with another task")
+	ErrRedisUnavailable                 = errors.New("redis is unavailable")
+	ErrFailedToMarshalPayload           = errors.New("failed to marshal payload")
+	ErrMissedTaskID                     = errors.New("missed task id in context")
+	ErrConcurrencyLimitReached          = errors.New("concurrency limit reached")
+	ErrRateLimitExceeded                = errors.New("rate limit exceeded")
+	ErrTenantFairShareExceeded          = errors.New("tenant fair share exceeded")
+	ErrFailedToSetQueueConfig           = errors.New("failed to set queue config")
+	ErrFailedToWatchQueueConfig         = errors.New("failed to watch queue config")
+	ErrQueueConfigNotFound              = errors.New("queue config not found")
+	ErrQueuesAreEmpty                   = errors.New("queues are empty")
+	ErrQueuePaused                      = errors.New("queue is paused")
+	ErrFailedToPauseQueue               = errors.New("failed to pause queue")
+	ErrFailedToResumeQueue              = errors.New("failed to resume queue")
+	ErrFailedToGetHealth                = errors.New("failed to get health")
+	ErrFailedToAutoscale                = errors.New("failed to autoscale")
+	ErrFailedToLoadConfig               = errors.New("failed to load config")
+	ErrInvalidConfig                    = errors.New("invalid config")
+	ErrUnsupportedConfigFormat          = errors.New("unsupported config format")
+	ErrInvalidTaskOption                = errors.New("invalid task option")
+	ErrInvalidQueueServerOption         = errors.New("invalid queue server option")
+	ErrInvalidSchedulerOption           = errors.New("invalid scheduler option")
+	ErrInvalidEnqueuerOption            = errors.New("invalid enqueuer option")
+	ErrScheduleIDIsEmpty                = errors.New("schedule id is empty")
+	ErrScheduleAlreadyExists            = errors.New("schedule already exists")
+	ErrScheduleNotFound                 = errors.New("schedule not found")
+	ErrFailedToAddSchedule              = errors.New("failed to add schedule")
+	ErrFailedToUpdateSchedule           = errors.New("failed to update schedule")
+	ErrFailedToRemoveSchedule           = errors.New("failed to remove schedule")
+	ErrFailedToWatchSchedules           = errors.New("failed to watch schedules")
+	ErrUnknownTimeZone                  = errors.New("unknown time zone")
+	ErrInvalidCronSpec                  = errors.New("invalid cron spec")
+	ErrSchedulerIsShutDown              = errors.New("scheduler is shut down")
+	ErrInvalidCalendar                  = errors.New("invalid calendar")
+	ErrFailedToGetScheduleHistory       = errors.New("failed to get schedule history")
+	ErrFailedToListQueues               = errors.New("failed to list queues")
+	ErrFailedToGetQueueStats            = errors.New("failed to get queue stats")
+	ErrFailedToListTasks                = errors.New("failed to list tasks")
+	ErrFailedToGetTask                  = errors.New("failed to get task")
+	ErrUnknownTaskState                 = errors.New("unknown task state")
+	ErrInvalidReplayOption              = errors.New("invalid replay option")
+	ErrFailedToReplayTasks              = errors.New("failed to replay tasks")
 )
@@ -0,0 +1,94 @@
+package asyncer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/hibiken/asynq"
+)
+
+// Health statuses.
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+)
+
+type (
+	// Health is the health report of the queues.
+	Health struct {
+		// Status is "ok" if all queues are processed, "degraded" if any queue is paused.
+		Status string `json:"status"`
+		// Queues is the list of the queue states.
+		Queues []QueueHealth `json:"queues"`
+	}
+
+	// QueueHealth is the health state of a queue.
+	QueueHealth struct {
+		Name    string `json:"name"`
+		Paused  bool   `json:"paused"`
+		Size    int    `json:"size"`
+		Pending int    `json:"pending"`
+		Active  int    `json:"active"`
+		// Latency is the time in milliseconds the oldest pending task is waiting to be processed.
+		Latency int64 `json:"latency_ms"`
+	}
+)
+
+// Health returns the health report of the given queues.
+// If no queue is given, all queues are reported.
+func (i *Inspector) Health(queues ...string) (Health, error) {
+	if len(queues) == 0 {
+		var err error
+		if queues, err = i.asynq.Queues(); err != nil {
+			return Health{}, errors.Join(ErrFailedToGetHealth, err)
+		}
+	}
+
+	health := Health{Status: HealthStatusOK, Queues: make([]QueueHealth, 0, len(queues))}
+	for _, queue := range queues {
+		info, err := i.asynq.GetQueueInfo(queue)
+		if err != nil {
+			if errors.Is(err, asynq.ErrQueueNotFound) {
+				// The queue has no tasks yet.
+				health.Queues = append(health.Queues, QueueHealth{Name: queue})
+				continue
+			}
+			return Health{}, errors.Join(ErrFailedToGetHealth, err)
+		}
+
+		if info.Paused {
+			health.Status = HealthStatusDegraded
+		}
+		health.Queues = append(health.Queues, QueueHealth{
+			Name:    queue,
+			Paused:  info.Paused,
+			Size:    info.Size,
+			Pending: info.Pending,
+			Active:  info.Active,
+			Latency: info.Latency.Milliseconds(),
+		})
+	}
+
+	return health, nil
+}
+
+// HealthHandler returns an HTTP handler reporting the health of the given queues as JSON.
+// If no queue is given, all queues are reported.
+// Paused queues are reported as degraded with 200 status code,
+// so pausing a queue doesn't make orchestrators restart the workers.
+// The handler responds with 503 status code if redis is not reachable.
+func HealthHandler(inspector *Inspector, queues ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		health, err := inspector.Health(queues...)
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "error": err.Error()})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(health)
+	})
+}
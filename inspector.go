@@ -0,0 +1,105 @@
+package asyncer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// Inspector is a wrapper for asynq.Inspector.
+	// It is used to administrate queues, e.g. pause and resume them during incident response,
+	// and to inspect their tasks with the payloads decoded into the registered payload types.
+	Inspector struct {
+		asynq    *asynq.Inspector
+		redis    redis.UniversalClient
+		payloads map[string]payloadDecoder // by task name
+	}
+
+	// InspectorOption is a function that configures an Inspector.
+	InspectorOption func(*Inspector)
+
+	// payloadDecoder decodes the task payloads into the payload type of a task.
+	payloadDecoder func(payload []byte) (any, error)
+)
+
+// NewInspector creates a new instance of Inspector.
+func NewInspector(redisClient redis.UniversalClient, opts ...InspectorOption) *Inspector {
+	i := &Inspector{
+		asynq:    asynq.NewInspectorFromRedisClient(redisClient),
+		redis:    redisClient,
+		payloads: make(map[string]payloadDecoder),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// WithInspectorHandlers registers the payload types of the task handlers,
+// so the payloads of their tasks are decoded the same way the handlers do.
+// Only the handlers created with HandlerFunc and BatchHandlerFunc have a known payload type,
+// the payloads of other tasks are left undecoded.
+func WithInspectorHandlers(handlers ...TaskHandler) InspectorOption {
+	return func(i *Inspector) {
+		for _, h := range handlers {
+			if d, ok := h.(interface{ decodePayload([]byte) (any, error) }); ok {
+				i.payloads[h.TaskName()] = d.decodePayload
+			}
+		}
+	}
+}
+
+// WithInspectorPayload registers the payload type of the task,
+// so its payloads are decoded the same way a HandlerFunc with this payload type does.
+// E.g.: asyncer.WithInspectorPayload[EmailPayload]("email:send").
+func WithInspectorPayload[Payload any](taskName string) InspectorOption {
+	return func(i *Inspector) {
+		i.payloads[taskName] = func(payload []byte) (any, error) {
+			return unmarshalPayload[Payload](payload)
+		}
+	}
+}
+
+// PauseQueue pauses processing of the queue.
+// Workers keep running, but don't fetch new tasks from the queue until it is resumed.
+// Tasks can still be enqueued to a paused queue, unless the enqueuer rejects them
+// (see WithRejectPausedQueues).
+func (i *Inspector) PauseQueue(queue string) error {
+	if err := i.asynq.PauseQueue(queue); err != nil {
+		return errors.Join(ErrFailedToPauseQueue, err)
+	}
+	return nil
+}
+
+// ResumeQueue resumes processing of the paused queue.
+func (i *Inspector) ResumeQueue(queue string) error {
+	if err := i.asynq.UnpauseQueue(queue); err != nil {
+		return errors.Join(ErrFailedToResumeQueue, err)
+	}
+	return nil
+}
+
+// IsQueuePaused reports whether the queue is paused.
+// A queue without any tasks is reported as not paused.
+func (i *Inspector) IsQueuePaused(ctx context.Context, queue string) (bool, error) {
+	return isQueuePaused(ctx, i.redis, queue)
+}
+
+// isQueuePaused reports whether the queue is paused.
+// It reads the flag asynq sets on pause directly, instead of the whole queue info,
+// since it's checked on every enqueue.
+func isQueuePaused(ctx context.Context, rdb redis.UniversalClient, queue string) (bool, error) {
+	n, err := rdb.Exists(ctx, queuePausedKey(queue)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// queuePausedKey returns the redis key asynq sets while the queue is paused.
+func queuePausedKey(queue string) string {
+	return "asynq:{" + queue + "}:paused"
+}
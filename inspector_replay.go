@@ -0,0 +1,332 @@
+package asyncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Default replay options.
+const (
+	defaultReplayRate     = 100 // Default number of tasks replayed per second
+	replayListingPageSize = 100 // Number of archived tasks fetched at once
+)
+
+type (
+	// ReplayOption is a function that configures a replay of archived tasks (see Inspector.ReplayArchived).
+	ReplayOption func(*replayConfig)
+
+	// replayConfig is the config of a replay of archived tasks.
+	replayConfig struct {
+		taskName     string
+		errPattern   *regexp.Regexp
+		failedAfter  time.Time
+		failedBefore time.Time
+		fieldPath    []string
+		fieldValue   any
+		dryRun       bool
+		rate         int
+		patch        func(taskName string, payload []byte) ([]byte, error)
+		errs         []error
+	}
+
+	// ReplayResult is the result of a replay of archived tasks.
+	ReplayResult struct {
+		// DryRun reports whether the tasks were only counted, without replaying them.
+		DryRun bool
+		// Matched is the number of archived tasks matching the filters.
+		Matched int
+		// MatchedByTaskName is the number of matching tasks per task name.
+		MatchedByTaskName map[string]int
+		// Replayed is the number of tasks moved back to the queue.
+		Replayed int
+		// Failed is the number of matching tasks which failed to replay, they are left archived.
+		Failed int
+	}
+)
+
+// WithReplayTaskName replays only the archived tasks with the given name.
+func WithReplayTaskName(taskName string) ReplayOption {
+	return func(cnf *replayConfig) {
+		cnf.taskName = taskName
+	}
+}
+
+// WithReplayErrorMatching replays only the archived tasks whose last error matches the regular expression.
+func WithReplayErrorMatching(pattern string) ReplayOption {
+	return func(cnf *replayConfig) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			cnf.errs = append(cnf.errs, fmt.Errorf("invalid error pattern %q: %w", pattern, err))
+			return
+		}
+		cnf.errPattern = re
+	}
+}
+
+// WithReplayFailedBetween replays only the archived tasks which last failed within the time range,
+// e.g. during an outage. A zero bound leaves the range open on that side.
+func WithReplayFailedBetween(from, to time.Time) ReplayOption {
+	return func(cnf *replayConfig) {
+		if !from.IsZero() && !to.IsZero() && to.Before(from) {
+			cnf.errs = append(cnf.errs, fmt.Errorf("failed time range end %v is before its start %v", to, from))
+			return
+		}
+		cnf.failedAfter = from
+		cnf.failedBefore = to
+	}
+}
+
+// WithReplayPayloadField replays only the archived tasks whose JSON payload has the field with the given value.
+// Nested fields are separated by dots, e.g. "customer.id".
+// The values are compared as JSON, so 42 matches both int and float64 payload fields.
+func WithReplayPayloadField(path string, value any) ReplayOption {
+	return func(cnf *replayConfig) {
+		if path == "" {
+			cnf.errs = append(cnf.errs, errors.New("payload field path must not be empty"))
+			return
+		}
+		normalized, err := normalizeJSONValue(value)
+		if err != nil {
+			cnf.errs = append(cnf.errs, fmt.Errorf("invalid payload field value: %w", err))
+			return
+		}
+		cnf.fieldPath = strings.Split(path, ".")
+		cnf.fieldValue = normalized
+	}
+}
+
+// WithReplayDryRun only counts the matching archived tasks, without replaying them.
+func WithReplayDryRun() ReplayOption {
+	return func(cnf *replayConfig) {
+		cnf.dryRun = true
+	}
+}
+
+// WithReplayRate sets the maximum number of tasks replayed per second, so the workers are not flooded.
+// Zero or negative rate replays the tasks without a limit. The default is 100 tasks per second.
+func WithReplayRate(perSecond int) ReplayOption {
+	return func(cnf *replayConfig) {
+		cnf.rate = perSecond
+	}
+}
+
+// WithReplayPayloadPatch changes the payloads of the tasks before replaying them,
+// e.g. to fix a field that made them fail.
+// The patched task is enqueued as a new task with the same name, queue, group, max retry, timeout and retention,
+// and the archived one is deleted. The deadline is kept if it's not passed yet.
+// The uniqueness of the archived task is lost, asynq doesn't expose it.
+// If the function returns an error, the task is left archived and counted as failed.
+func WithReplayPayloadPatch(fn func(taskName string, payload []byte) ([]byte, error)) ReplayOption {
+	return func(cnf *replayConfig) {
+		cnf.patch = fn
+	}
+}
+
+// ReplayArchived moves the archived tasks of the queue matching all the filters back to the queue to be processed again.
+// Without filters, all archived tasks of the queue are replayed.
+// E.g. to check how many tasks failed with a timeout during an outage, and then replay them:
+//
+//	opts := []asyncer.ReplayOption{
+//		asyncer.WithReplayTaskName("email:send"),
+//		asyncer.WithReplayErrorMatching("i/o timeout"),
+//		asyncer.WithReplayFailedBetween(outageStart, outageEnd),
+//	}
+//	res, err := inspector.ReplayArchived(ctx, "default", append(opts, asyncer.WithReplayDryRun())...)
+//	// ... check res.Matched ...
+//	res, err = inspector.ReplayArchived(ctx, "default", opts...)
+//
+// The archive is streamed page by page, so the tasks are not collected in memory.
+// It returns the result so far along with an error if the context is done or any task fails to replay.
+func (i *Inspector) ReplayArchived(ctx context.Context, queue string, opts ...ReplayOption) (ReplayResult, error) {
+	cnf := replayConfig{rate: defaultReplayRate}
+	for _, opt := range opts {
+		opt(&cnf)
+	}
+	if len(cnf.errs) > 0 {
+		return ReplayResult{}, errors.Join(append([]error{ErrInvalidReplayOption}, cnf.errs...)...)
+	}
+
+	res := ReplayResult{DryRun: cnf.dryRun, MatchedByTaskName: make(map[string]int)}
+
+	var tick <-chan time.Time
+	if interval := time.Second / time.Duration(max(cnf.rate, 1)); cnf.rate > 0 && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// The patched tasks are enqueued as new ones.
+	var client *asynq.Client
+	if cnf.patch != nil {
+		client = asynq.NewClientFromRedisClient(i.redis)
+	}
+
+	// The archive is listed page by page, and the matching tasks of every page are replayed before the next one.
+	// The replayed tasks leave the archive and shift the pages, so the listing resumes after the tasks left in it.
+	// The tasks archived again during the replay are appended to the archive, they are skipped.
+	started := time.Now()
+	kept := 0 // number of listed tasks left in the archive
+	var errs []error
+listing:
+	for {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		page, skip := archivePage(kept)
+		tasks, err := i.asynq.ListArchivedTasks(queue, asynq.Page(page), asynq.PageSize(replayListingPageSize))
+		if err != nil {
+			if !errors.Is(err, asynq.ErrQueueNotFound) {
+				errs = append(errs, err)
+			}
+			break
+		}
+		if len(tasks) <= skip {
+			break
+		}
+
+		for _, t := range tasks[skip:] {
+			if !cnf.matches(t) || t.LastFailedAt.After(started) {
+				kept++
+				continue
+			}
+			res.Matched++
+			res.MatchedByTaskName[t.Type]++
+			if cnf.dryRun {
+				kept++
+				continue
+			}
+
+			if res.Replayed+res.Failed > 0 && tick != nil {
+				select {
+				case <-ctx.Done():
+				case <-tick:
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				errs = append(errs, err)
+				break listing
+			}
+
+			if err := i.replay(client, t, cnf.patch); err != nil {
+				res.Failed++
+				kept++
+				errs = append(errs, fmt.Errorf("task %s: %w", t.ID, err))
+				continue
+			}
+			res.Replayed++
+		}
+		if len(tasks) < replayListingPageSize {
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return res, errors.Join(append([]error{ErrFailedToReplayTasks}, errs...)...)
+	}
+	return res, nil
+}
+
+// archivePage returns the page of the archived tasks listing which contains the task at the given offset,
+// and the number of tasks on the page before it.
+func archivePage(offset int) (page, skip int) {
+	return offset/replayListingPageSize + 1, offset % replayListingPageSize
+}
+
+// replay moves the archived task back to the queue.
+// The task with a patched payload is enqueued as a new task with the client, and the archived one is deleted.
+func (i *Inspector) replay(client *asynq.Client, t *asynq.TaskInfo, patch func(string, []byte) ([]byte, error)) error {
+	if patch == nil {
+		return i.asynq.RunTask(t.Queue, t.ID)
+	}
+
+	payload, err := patch(t.Type, t.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to patch payload: %w", err)
+	}
+
+	opts := []asynq.Option{asynq.Queue(t.Queue), asynq.MaxRetry(t.MaxRetry)}
+	if t.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(t.Timeout))
+	}
+	if t.Retention > 0 {
+		opts = append(opts, asynq.Retention(t.Retention))
+	}
+	if t.Deadline.After(time.Now()) {
+		opts = append(opts, asynq.Deadline(t.Deadline))
+	}
+	if t.Group != "" {
+		opts = append(opts, asynq.Group(t.Group))
+	}
+	if _, err := client.Enqueue(asynq.NewTask(t.Type, payload), opts...); err != nil {
+		return err
+	}
+	if err := i.asynq.DeleteTask(t.Queue, t.ID); err != nil {
+		return fmt.Errorf("patched task is enqueued, but the archived one is not deleted: %w", err)
+	}
+	return nil
+}
+
+// matches reports whether the archived task matches the filters of the replay.
+func (cnf *replayConfig) matches(t *asynq.TaskInfo) bool {
+	if cnf.taskName != "" && t.Type != cnf.taskName {
+		return false
+	}
+	if cnf.errPattern != nil && !cnf.errPattern.MatchString(t.LastErr) {
+		return false
+	}
+	if !cnf.failedAfter.IsZero() && t.LastFailedAt.Before(cnf.failedAfter) {
+		return false
+	}
+	if !cnf.failedBefore.IsZero() && t.LastFailedAt.After(cnf.failedBefore) {
+		return false
+	}
+	if cnf.fieldPath != nil {
+		var payload any
+		if err := json.Unmarshal(t.Payload, &payload); err != nil {
+			return false
+		}
+		value, ok := jsonField(payload, cnf.fieldPath)
+		if !ok || !reflect.DeepEqual(value, cnf.fieldValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonField returns the value of the nested field of the decoded JSON value.
+func jsonField(v any, path []string) (any, bool) {
+	for _, name := range path {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[name]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// normalizeJSONValue returns the value as decoded from its JSON representation,
+// so it can be compared with the decoded payload fields.
+func normalizeJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var res any
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package asyncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestArchivePage(t *testing.T) {
+	tests := []struct {
+		offset   int
+		wantPage int
+		wantSkip int
+	}{
+		{offset: 0, wantPage: 1, wantSkip: 0},
+		{offset: 1, wantPage: 1, wantSkip: 1},
+		{offset: replayListingPageSize - 1, wantPage: 1, wantSkip: replayListingPageSize - 1},
+		{offset: replayListingPageSize, wantPage: 2, wantSkip: 0},
+		{offset: 2*replayListingPageSize + 5, wantPage: 3, wantSkip: 5},
+	}
+
+	for _, tt := range tests {
+		page, skip := archivePage(tt.offset)
+		if page != tt.wantPage || skip != tt.wantSkip {
+			t.Errorf("archivePage(%d) = %d, %d, want %d, %d", tt.offset, page, skip, tt.wantPage, tt.wantSkip)
+		}
+	}
+}
+
+func TestReplayConfigMatches(t *testing.T) {
+	failedAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	task := &asynq.TaskInfo{
+		Type:         "email:send",
+		Payload:      []byte(`{"customer":{"id":42,"plan":"enterprise"}}`),
+		LastErr:      "dial tcp: i/o timeout",
+		LastFailedAt: failedAt,
+	}
+
+	tests := []struct {
+		name string
+		opts []ReplayOption
+		want bool
+	}{
+		{name: "no filters", want: true},
+		{name: "task name", opts: []ReplayOption{WithReplayTaskName("email:send")}, want: true},
+		{name: "other task name", opts: []ReplayOption{WithReplayTaskName("sms:send")}},
+		{name: "error", opts: []ReplayOption{WithReplayErrorMatching("i/o timeout|refused")}, want: true},
+		{name: "other error", opts: []ReplayOption{WithReplayErrorMatching("refused")}},
+		{name: "failed within range", opts: []ReplayOption{WithReplayFailedBetween(failedAt.Add(-time.Hour), failedAt.Add(time.Hour))}, want: true},
+		{name: "failed before range", opts: []ReplayOption{WithReplayFailedBetween(failedAt.Add(time.Minute), time.Time{})}},
+		{name: "failed after range", opts: []ReplayOption{WithReplayFailedBetween(time.Time{}, failedAt.Add(-time.Minute))}},
+		{name: "payload field", opts: []ReplayOption{WithReplayPayloadField("customer.plan", "enterprise")}, want: true},
+		{name: "numeric payload field", opts: []ReplayOption{WithReplayPayloadField("customer.id", 42)}, want: true},
+		{name: "other payload field value", opts: []ReplayOption{WithReplayPayloadField("customer.plan", "free")}},
+		{name: "missing payload field", opts: []ReplayOption{WithReplayPayloadField("customer.region", "eu")}},
+		{
+			name: "all filters",
+			opts: []ReplayOption{
+				WithReplayTaskName("email:send"),
+				WithReplayErrorMatching("timeout"),
+				WithReplayPayloadField("customer.id", 42),
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cnf replayConfig
+			for _, opt := range tt.opts {
+				opt(&cnf)
+			}
+			if len(cnf.errs) > 0 {
+				t.Fatalf("replay options errors = %v", cnf.errs)
+			}
+			if got := cnf.matches(task); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayArchivedInvalidOptions(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+
+	_, err := NewInspector(rdb).ReplayArchived(context.Background(), "default",
+		WithReplayErrorMatching("("),
+		WithReplayFailedBetween(time.Now(), time.Now().Add(-time.Hour)),
+		WithReplayPayloadField("", 1),
+	)
+	if !errors.Is(err, ErrInvalidReplayOption) {
+		t.Errorf("ReplayArchived() error = %v, want %v", err, ErrInvalidReplayOption)
+	}
+}
@@ -0,0 +1,233 @@
+package asyncer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// defaultInspectorPageSize is the default number of tasks returned by ListTasks.
+const defaultInspectorPageSize = 30
+
+// TaskState is the state of a task in a queue.
+type TaskState string
+
+// Task states.
+const (
+	TaskStatePending     TaskState = "pending"     // The task is ready to be processed
+	TaskStateActive      TaskState = "active"      // The task is being processed
+	TaskStateScheduled   TaskState = "scheduled"   // The task is scheduled to be processed in the future
+	TaskStateRetry       TaskState = "retry"       // The task failed and is scheduled to be retried
+	TaskStateArchived    TaskState = "archived"    // The task exhausted its retries or was archived manually
+	TaskStateCompleted   TaskState = "completed"   // The task is processed and kept for its retention period
+	TaskStateAggregating TaskState = "aggregating" // The task is waiting in a group to be aggregated
+)
+
+type (
+	// QueueStats is the state of a queue.
+	QueueStats struct {
+		// Name is the name of the queue.
+		Name string `json:"name"`
+		// Paused reports whether the queue is paused.
+		Paused bool `json:"paused"`
+		// Size is the number of tasks in the queue, in any state.
+		Size int `json:"size"`
+		// Groups is the number of groups of the aggregating tasks.
+		Groups int `json:"groups"`
+		// Number of tasks in each state.
+		Pending     int `json:"pending"`
+		Active      int `json:"active"`
+		Scheduled   int `json:"scheduled"`
+		Retry       int `json:"retry"`
+		Archived    int `json:"archived"`
+		Completed   int `json:"completed"`
+		Aggregating int `json:"aggregating"`
+		// Processed and Failed are the numbers of tasks processed and failed today.
+		Processed int `json:"processed"`
+		Failed    int `json:"failed"`
+		// ProcessedTotal and FailedTotal are the numbers of tasks processed and failed since the queue was created.
+		ProcessedTotal int `json:"processed_total"`
+		FailedTotal    int `json:"failed_total"`
+		// Latency is the time the oldest pending task is waiting to be processed.
+		Latency time.Duration `json:"latency"`
+		// MemoryUsage is the memory used by the queue in redis, in bytes.
+		MemoryUsage int64 `json:"memory_usage"`
+		// Timestamp is the time the stats were taken.
+		Timestamp time.Time `json:"timestamp"`
+	}
+
+	// TaskInfo describes a task in a queue.
+	TaskInfo struct {
+		// ID is the identifier of the task.
+		ID string
+		// Queue is the name of the queue the task is in.
+		Queue string
+		// TaskName is the name of the task.
+		TaskName string
+		// State is the state of the task.
+		State TaskState
+		// Payload is the payload decoded into the payload type registered for the task name
+		// (see WithInspectorHandlers), nil if there is none or it can't be decoded.
+		Payload any
+		// PayloadError is the error of decoding the payload, if any.
+		PayloadError error
+		// RawPayload is the payload as enqueued.
+		RawPayload []byte
+		// Group is the group of the task, if it was enqueued with the Group option.
+		Group string
+		// MaxRetry is the maximum number of times the task can be retried.
+		MaxRetry int
+		// Retried is the number of times the task was retried.
+		Retried int
+		// LastErr is the error of the last failed attempt, if any.
+		LastErr string
+		// LastFailedAt is the time of the last failed attempt, zero if the task never failed.
+		LastFailedAt time.Time
+		// Timeout is the processing timeout of the task.
+		Timeout time.Duration
+		// Deadline is the processing deadline of the task.
+		Deadline time.Time
+		// NextProcessAt is the time the task is processed next, zero if it's not scheduled.
+		NextProcessAt time.Time
+		// CompletedAt is the time the task was processed, zero if it's not completed.
+		CompletedAt time.Time
+		// Retention is the time the task is kept after its completion.
+		Retention time.Duration
+		// Result is the result written by the handler, if any.
+		Result []byte
+	}
+)
+
+// Queues returns the names of the queues.
+func (i *Inspector) Queues() ([]string, error) {
+	queues, err := i.asynq.Queues()
+	if err != nil {
+		return nil, errors.Join(ErrFailedToListQueues, err)
+	}
+	return queues, nil
+}
+
+// QueueStats returns the state of the queue.
+func (i *Inspector) QueueStats(queue string) (QueueStats, error) {
+	info, err := i.asynq.GetQueueInfo(queue)
+	if err != nil {
+		return QueueStats{}, errors.Join(ErrFailedToGetQueueStats, err)
+	}
+	return QueueStats{
+		Name:           info.Queue,
+		Paused:         info.Paused,
+		Size:           info.Size,
+		Groups:         info.Groups,
+		Pending:        info.Pending,
+		Active:         info.Active,
+		Scheduled:      info.Scheduled,
+		Retry:          info.Retry,
+		Archived:       info.Archived,
+		Completed:      info.Completed,
+		Aggregating:    info.Aggregating,
+		Processed:      info.Processed,
+		Failed:         info.Failed,
+		ProcessedTotal: info.ProcessedTotal,
+		FailedTotal:    info.FailedTotal,
+		Latency:        info.Latency,
+		MemoryUsage:    info.MemoryUsage,
+		Timestamp:      info.Timestamp,
+	}, nil
+}
+
+// ListTasks returns a page of the tasks in the given state of the queue.
+// Pages start at 1, non-positive page size uses the default 30 tasks per page.
+// The aggregating tasks are listed per group, use ListAggregatingTasks for them.
+func (i *Inspector) ListTasks(queue string, state TaskState, page, pageSize int) ([]TaskInfo, error) {
+	if pageSize <= 0 {
+		pageSize = defaultInspectorPageSize
+	}
+	opts := []asynq.ListOption{asynq.Page(max(page, 1)), asynq.PageSize(pageSize)}
+
+	var list func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	switch state {
+	case TaskStatePending:
+		list = i.asynq.ListPendingTasks
+	case TaskStateActive:
+		list = i.asynq.ListActiveTasks
+	case TaskStateScheduled:
+		list = i.asynq.ListScheduledTasks
+	case TaskStateRetry:
+		list = i.asynq.ListRetryTasks
+	case TaskStateArchived:
+		list = i.asynq.ListArchivedTasks
+	case TaskStateCompleted:
+		list = i.asynq.ListCompletedTasks
+	default:
+		return nil, errors.Join(ErrFailedToListTasks, ErrUnknownTaskState, fmt.Errorf("%q", state))
+	}
+
+	tasks, err := list(queue, opts...)
+	if err != nil {
+		return nil, errors.Join(ErrFailedToListTasks, err)
+	}
+	return i.taskInfos(tasks), nil
+}
+
+// ListAggregatingTasks returns a page of the tasks of the group waiting to be aggregated.
+// Pages start at 1, non-positive page size uses the default 30 tasks per page.
+func (i *Inspector) ListAggregatingTasks(queue, group string, page, pageSize int) ([]TaskInfo, error) {
+	if pageSize <= 0 {
+		pageSize = defaultInspectorPageSize
+	}
+
+	tasks, err := i.asynq.ListAggregatingTasks(queue, group, asynq.Page(max(page, 1)), asynq.PageSize(pageSize))
+	if err != nil {
+		return nil, errors.Join(ErrFailedToListTasks, err)
+	}
+	return i.taskInfos(tasks), nil
+}
+
+// GetTask returns the task with the given ID in the queue.
+func (i *Inspector) GetTask(queue, id string) (TaskInfo, error) {
+	task, err := i.asynq.GetTaskInfo(queue, id)
+	if err != nil {
+		return TaskInfo{}, errors.Join(ErrFailedToGetTask, err)
+	}
+	return i.taskInfo(task), nil
+}
+
+// taskInfos converts the asynq task infos, decoding their payloads.
+func (i *Inspector) taskInfos(tasks []*asynq.TaskInfo) []TaskInfo {
+	res := make([]TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		res = append(res, i.taskInfo(t))
+	}
+	return res
+}
+
+// taskInfo converts the asynq task info, decoding its payload into the registered payload type.
+func (i *Inspector) taskInfo(t *asynq.TaskInfo) TaskInfo {
+	info := TaskInfo{
+		ID:            t.ID,
+		Queue:         t.Queue,
+		TaskName:      t.Type,
+		State:         TaskState(t.State.String()),
+		RawPayload:    t.Payload,
+		Group:         t.Group,
+		MaxRetry:      t.MaxRetry,
+		Retried:       t.Retried,
+		LastErr:       t.LastErr,
+		LastFailedAt:  t.LastFailedAt,
+		Timeout:       t.Timeout,
+		Deadline:      t.Deadline,
+		NextProcessAt: t.NextProcessAt,
+		CompletedAt:   t.CompletedAt,
+		Retention:     t.Retention,
+		Result:        t.Result,
+	}
+	if decode, ok := i.payloads[t.Type]; ok {
+		info.Payload, info.PayloadError = decode(t.Payload)
+		if info.PayloadError != nil {
+			info.Payload = nil
+		}
+	}
+	return info
+}
@@ -0,0 +1,10 @@
+package asyncer
+
+import "testing"
+
+func TestQueuePausedKey(t *testing.T) {
+	// The key must match the one asynq sets on pause (base.PausedKey).
+	if got, want := queuePausedKey("critical"), "asynq:{critical}:paused"; got != want {
+		t.Errorf("queuePausedKey() = %q, want %q", got, want)
+	}
+}
@@ -1,22 +1,85 @@
 package asyncer
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/hibiken/asynq"
 )
 
-// parseLocation parses the given timeZone string and returns a pointer to a time.Location.
-// If the timeZone string is invalid, it returns a pointer to the UTC time.Location.
+// loadLocation returns the location with the given name.
 //
-// If the name is "" or "UTC", LoadLocation returns UTC.
-// If the name is "Local", LoadLocation returns Local.
+// If the name is "" or "UTC", it returns UTC.
+// If the name is "Local", it returns Local.
 //
 // Otherwise, the name is taken to be a location name corresponding to a file
 // in the IANA Time Zone database, such as "America/New_York".
-func parseLocation(timeZone string) *time.Location {
-	// parse location from string and set it to the config
+// It returns an error wrapping ErrUnknownTimeZone if the name is invalid.
+func loadLocation(timeZone string) (*time.Location, error) {
 	loc, err := time.LoadLocation(timeZone)
 	if err != nil {
-		return time.UTC
+		return nil, errors.Join(ErrUnknownTimeZone, err)
+	}
+	return loc, nil
+}
+
+// timeZoneOption is the option to set the time zone of a schedule.
+type timeZoneOption struct {
+	name string
+}
+
+// String returns the string representation of the option.
+func (o timeZoneOption) String() string { return fmt.Sprintf("TimeZone(%q)", o.name) }
+
+// Type returns the type of the option.
+func (o timeZoneOption) Type() asynq.OptionType { return timeZoneOpt }
+
+// Value returns the value of the option.
+func (o timeZoneOption) Value() any { return o.name }
+
+// TimeZone sets the time zone the cron spec of a schedule is evaluated in, e.g. "Europe/Berlin",
+// instead of the scheduler location. Scheduling fails with an error wrapping ErrUnknownTimeZone if it's unknown.
+func TimeZone(name string) TaskOption {
+	if name == "" {
+		return invalid(nil, "time zone must not be empty")
+	}
+	return timeZoneOption{name: name}
+}
+
+// cronSpecWithTimeZone returns the cron spec evaluated in the time zone set by the TimeZone option.
+// The time zone set in the cron spec itself (the CRON_TZ= or TZ= prefix) is validated as well.
+func cronSpecWithTimeZone(cronSpec string, opts []asynq.Option) (string, error) {
+	specZone := cronSpecTimeZone(cronSpec)
+	if specZone != "" {
+		if _, err := loadLocation(specZone); err != nil {
+			return "", err
+		}
+	}
+
+	opt, ok := findOption[timeZoneOption](opts)
+	if !ok {
+		return cronSpec, nil
+	}
+	if specZone != "" {
+		return "", fmt.Errorf("cron spec %q already sets the time zone", cronSpec)
+	}
+	loc, err := loadLocation(opt.name)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("CRON_TZ=%s %s", loc, cronSpec), nil
+}
+
+// cronSpecTimeZone returns the time zone set in the cron spec with the CRON_TZ= or TZ= prefix, if any.
+func cronSpecTimeZone(cronSpec string) string {
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(cronSpec), prefix); ok {
+			name, _, _ := strings.Cut(rest, " ")
+			return name
+		}
 	}
-	return loc
+	return ""
 }
@@ -29,3 +29,23 @@ func castToAsynqLogLevel(level string) asynq.LogLevel {
 		return asynq.InfoLevel
 	}
 }
+
+// asynqLogLevel converts a string representation of a log level to the corresponding asynq.LogLevel.
+// Unlike castToAsynqLogLevel, it returns an unspecified level if the input is not a recognized log level,
+// so the server reports it in strict mode before falling back to the info level.
+func asynqLogLevel(level string) asynq.LogLevel {
+	if !isValidLogLevel(level) {
+		return 0
+	}
+	return castToAsynqLogLevel(level)
+}
+
+// isValidLogLevel reports whether the string is a recognized log level.
+func isValidLogLevel(level string) bool {
+	switch level {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+package asyncer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// invalidOption is returned by the option constructors for input that can't be used,
+// e.g. a non-positive timeout or an empty tenant field. It holds the coerced option,
+// which is used instead in non-strict mode, and the validation error, which is reported in strict mode.
+type invalidOption struct {
+	opt asynq.Option // nil if the option is dropped in non-strict mode
+	err error
+}
+
+// invalid returns an invalid option holding the coerced option and the validation error.
+func invalid(opt asynq.Option, format string, args ...any) TaskOption {
+	return invalidOption{opt: opt, err: fmt.Errorf(format, args...)}
+}
+
+// String returns the string representation of the coerced option.
+func (o invalidOption) String() string {
+	if o.opt != nil {
+		return o.opt.String()
+	}
+	return fmt.Sprintf("Invalid(%v)", o.err)
+}
+
+// Type returns the type of the coerced option.
+func (o invalidOption) Type() asynq.OptionType {
+	if o.opt != nil {
+		return o.opt.Type()
+	}
+	return invalidOpt
+}
+
+// Value returns the value of the coerced option.
+func (o invalidOption) Value() any {
+	if o.opt != nil {
+		return o.opt.Value()
+	}
+	return o.err
+}
+
+// normalizeOptions replaces the invalid options with their coerced values and drops nil options.
+// In strict mode it returns an error describing all invalid options instead.
+func normalizeOptions(opts []asynq.Option, strict bool) ([]asynq.Option, error) {
+	res := make([]asynq.Option, 0, len(opts))
+	var errs []error
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case nil:
+			if strict {
+				errs = append(errs, errors.New("option is nil"))
+			}
+		case invalidOption:
+			if strict {
+				errs = append(errs, o.err)
+				continue
+			}
+			if o.opt != nil {
+				res = append(res, o.opt)
+			}
+		default:
+			if strict {
+				if err := validateOption(opt); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
+			res = append(res, opt)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(append([]error{ErrInvalidTaskOption}, errs...)...)
+	}
+	return res, nil
+}
+
+// validateOption returns an error if the value of the asynq option is invalid,
+// e.g. an option created with asynq directly, or a deadline which passed since the option was created.
+func validateOption(opt asynq.Option) error {
+	switch opt.Type() {
+	case asynq.MaxRetryOpt:
+		if n, _ := opt.Value().(int); n < 0 {
+			return fmt.Errorf("max retry must not be negative, got %d", n)
+		}
+	case asynq.TimeoutOpt:
+		if d, _ := opt.Value().(time.Duration); d < 0 {
+			return fmt.Errorf("timeout must not be negative, got %v", d)
+		}
+	case asynq.DeadlineOpt:
+		if t, _ := opt.Value().(time.Time); !t.IsZero() && t.Before(time.Now()) {
+			return fmt.Errorf("deadline must be in the future, got %v", t)
+		}
+	case asynq.UniqueOpt:
+		if d, _ := opt.Value().(time.Duration); d < time.Second {
+			return fmt.Errorf("unique ttl must be at least 1s, got %v", d)
+		}
+	case asynq.QueueOpt:
+		if v, _ := opt.Value().(string); strings.TrimSpace(v) == "" {
+			return errors.New("queue must not be empty")
+		}
+	case asynq.TaskIDOpt:
+		if v, _ := opt.Value().(string); strings.TrimSpace(v) == "" {
+			return errors.New("task id must not be empty")
+		}
+	case asynq.GroupOpt:
+		if v, _ := opt.Value().(string); strings.TrimSpace(v) == "" {
+			return errors.New("group must not be empty")
+		}
+	}
+	return nil
+}
+
+// ValidateTaskOptions returns an error describing all invalid task options,
+// e.g. an empty task ID or a deadline in the past.
+// The option constructors coerce invalid input to the closest valid value (e.g. Timeout(0) to one second),
+// which is used unless the enqueuer, queue server or scheduler runs in strict mode
+// (see WithStrictOptionsEnq, QueueServer.SetStrictOptions and SchedulerServer.SetStrictOptions).
+// This function can be used in tests to catch misconfigurations early.
+func ValidateTaskOptions(opts ...TaskOption) error {
+	_, err := normalizeOptions(opts, true)
+	return err
+}
+
+// normalizeQueueServerConfig replaces the invalid values of the queue server config with the closest valid ones.
+// It returns an error describing all replaced values, which is reported in strict mode.
+func normalizeQueueServerConfig(cnf *asynq.Config) error {
+	var errs []error
+	if cnf.Concurrency < 1 {
+		errs = append(errs, fmt.Errorf("concurrency must be positive, got %d", cnf.Concurrency))
+		cnf.Concurrency = 1
+	}
+	queues := make(map[string]int, len(cnf.Queues))
+	for name, priority := range cnf.Queues {
+		if strings.TrimSpace(name) == "" || priority < 1 {
+			errs = append(errs, fmt.Errorf("queue %q must have a name and a positive priority, got %d", name, priority))
+			continue
+		}
+		queues[name] = priority
+	}
+	if len(queues) == 0 {
+		queues = map[string]int{defaultQueueName: defaultQueuePriority}
+	}
+	cnf.Queues = queues
+	if cnf.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("shutdown timeout must not be negative, got %v", cnf.ShutdownTimeout))
+		cnf.ShutdownTimeout = 0
+	}
+	if cnf.LogLevel < asynq.DebugLevel || cnf.LogLevel > asynq.FatalLevel {
+		errs = append(errs, errors.New("log level is unknown"))
+		cnf.LogLevel = asynq.InfoLevel
+	}
+	if cnf.GroupGracePeriod < 0 || (cnf.GroupGracePeriod > 0 && cnf.GroupGracePeriod < time.Second) {
+		errs = append(errs, fmt.Errorf("group grace period must be at least 1s, got %v", cnf.GroupGracePeriod))
+		cnf.GroupGracePeriod = time.Second
+	}
+	if cnf.GroupMaxDelay < 0 {
+		errs = append(errs, fmt.Errorf("group max delay must not be negative, got %v", cnf.GroupMaxDelay))
+		cnf.GroupMaxDelay = 0
+	}
+	if cnf.GroupMaxSize < 0 {
+		errs = append(errs, fmt.Errorf("group max size must not be negative, got %d", cnf.GroupMaxSize))
+		cnf.GroupMaxSize = 0
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrInvalidQueueServerOption}, errs...)...)
+	}
+	return nil
+}
+
+// normalizeSchedulerOpts replaces the invalid values of the scheduler options with the closest valid ones.
+// It returns an error describing all replaced values, which is reported in strict mode.
+func normalizeSchedulerOpts(opts *asynq.SchedulerOpts) error {
+	var errs []error
+	if opts.Location == nil {
+		errs = append(errs, fmt.Errorf("location is unknown, falling back to UTC: %w", ErrUnknownTimeZone))
+		opts.Location = time.UTC
+	}
+	if opts.LogLevel < asynq.DebugLevel || opts.LogLevel > asynq.FatalLevel {
+		errs = append(errs, errors.New("log level is unknown"))
+		opts.LogLevel = asynq.InfoLevel
+	}
+	if opts.HeartbeatInterval < 0 {
+		errs = append(errs, fmt.Errorf("heartbeat interval must not be negative, got %v", opts.HeartbeatInterval))
+		opts.HeartbeatInterval = 0
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrInvalidSchedulerOption}, errs...)...)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package asyncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestValidateTaskOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     TaskOption
+		wantErr bool
+	}{
+		{name: "max retry", opt: MaxRetry(3)},
+		{name: "negative max retry", opt: MaxRetry(-1), wantErr: true},
+		{name: "timeout", opt: Timeout(time.Minute)},
+		{name: "zero timeout", opt: Timeout(0), wantErr: true},
+		{name: "deadline", opt: Deadline(time.Now().Add(time.Hour))},
+		{name: "zero deadline", opt: Deadline(time.Time{}), wantErr: true},
+		{name: "past deadline", opt: Deadline(time.Now().Add(-time.Hour)), wantErr: true},
+		{name: "unique", opt: Unique(time.Minute)},
+		{name: "zero unique ttl", opt: Unique(0), wantErr: true},
+		{name: "process at", opt: ProcessAt(time.Now().Add(time.Hour))},
+		{name: "zero process at", opt: ProcessAt(time.Time{}), wantErr: true},
+		{name: "process in", opt: ProcessIn(time.Minute)},
+		{name: "zero process in", opt: ProcessIn(0), wantErr: true},
+		{name: "process at spec", opt: ProcessAtSpec("@at 2999-12-01 09:00 Europe/Berlin")},
+		{name: "process at rule", opt: ProcessAtSpec("RRULE:FREQ=DAILY;BYHOUR=9")},
+		{name: "past process at spec", opt: ProcessAtSpec("@at 2020-12-01 09:00"), wantErr: true},
+		{name: "ended process at rule", opt: ProcessAtSpec("RRULE:FREQ=DAILY;UNTIL=20200101"), wantErr: true},
+		{name: "invalid process at spec", opt: ProcessAtSpec("@at tomorrow"), wantErr: true},
+		{name: "empty task id", opt: TaskID(""), wantErr: true},
+		{name: "empty group", opt: Group(""), wantErr: true},
+		{name: "asynq unique with zero ttl", opt: asynq.Unique(0), wantErr: true},
+		{name: "nil option", opt: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaskOptions(tt.opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTaskOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidTaskOption) {
+				t.Errorf("ValidateTaskOptions() error = %v, want it to wrap ErrInvalidTaskOption", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeOptionsCoercesInvalidOptions(t *testing.T) {
+	opts, err := normalizeOptions([]TaskOption{
+		Timeout(0),
+		Deadline(time.Time{}),
+		Unique(0),
+		ProcessAt(time.Time{}),
+		ProcessAtSpec("@at 2020-12-01 09:00"),
+		TaskID(""),
+	}, false)
+	if err != nil {
+		t.Fatalf("normalizeOptions() error = %v", err)
+	}
+
+	// The coerced options are plain asynq options, the empty task ID is dropped.
+	want := []asynq.OptionType{asynq.TimeoutOpt, asynq.DeadlineOpt, asynq.UniqueOpt, asynq.ProcessAtOpt, asynq.ProcessAtOpt}
+	if len(opts) != len(want) {
+		t.Fatalf("normalizeOptions() = %v, want %d options", opts, len(want))
+	}
+	for i, opt := range opts {
+		if _, ok := opt.(invalidOption); ok || opt.Type() != want[i] {
+			t.Errorf("normalizeOptions()[%d] = %#v, want an asynq option of type %v", i, opt, want[i])
+		}
+	}
+	if d, _ := opts[0].Value().(time.Duration); d != time.Second {
+		t.Errorf("coerced timeout = %v, want %v", d, time.Second)
+	}
+	if at, _ := opts[4].Value().(time.Time); !at.After(time.Now()) {
+		t.Errorf("coerced process at spec = %v, want a time in the future", at)
+	}
+}
+
+func TestNormalizeQueueServerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []QueueServerOption
+		check   func(cnf asynq.Config) bool
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			check: func(cnf asynq.Config) bool { return cnf.Queues[defaultQueueName] == defaultQueuePriority },
+		},
+		{
+			name:    "non-positive concurrency",
+			opts:    []QueueServerOption{WithQueueConcurrency(0)},
+			check:   func(cnf asynq.Config) bool { return cnf.Concurrency == 1 },
+			wantErr: true,
+		},
+		{
+			name: "invalid queues",
+			opts: []QueueServerOption{WithQueues(map[string]int{"critical": 6, "": 3, "low": 0})},
+			check: func(cnf asynq.Config) bool {
+				return len(cnf.Queues) == 1 && cnf.Queues["critical"] == 6
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no valid queue",
+			opts:    []QueueServerOption{WithQueue("default", 0)},
+			check:   func(cnf asynq.Config) bool { return cnf.Queues[defaultQueueName] == defaultQueuePriority },
+			wantErr: true,
+		},
+		{
+			name:    "negative shutdown timeout",
+			opts:    []QueueServerOption{WithQueueShutdownTimeout(-time.Second)},
+			check:   func(cnf asynq.Config) bool { return cnf.ShutdownTimeout == 0 },
+			wantErr: true,
+		},
+		{
+			name:    "unknown log level",
+			opts:    []QueueServerOption{WithQueueLogLevel("loud")},
+			check:   func(cnf asynq.Config) bool { return cnf.LogLevel == asynq.InfoLevel },
+			wantErr: true,
+		},
+		{
+			name:    "short group grace period",
+			opts:    []QueueServerOption{WithQueueGroupGracePeriod(time.Millisecond)},
+			check:   func(cnf asynq.Config) bool { return cnf.GroupGracePeriod == time.Second },
+			wantErr: true,
+		},
+		{
+			name:    "negative group max delay",
+			opts:    []QueueServerOption{WithQueueGroupMaxDelay(-time.Second)},
+			check:   func(cnf asynq.Config) bool { return cnf.GroupMaxDelay == 0 },
+			wantErr: true,
+		},
+		{
+			name:    "negative group max size",
+			opts:    []QueueServerOption{WithQueueGroupMaxSize(-1)},
+			check:   func(cnf asynq.Config) bool { return cnf.GroupMaxSize == 0 },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnf := defaultQueueServerConfig()
+			for _, opt := range tt.opts {
+				opt(&cnf)
+			}
+
+			err := normalizeQueueServerConfig(&cnf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeQueueServerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidQueueServerOption) {
+				t.Errorf("normalizeQueueServerConfig() error = %v, want it to wrap ErrInvalidQueueServerOption", err)
+			}
+			if !tt.check(cnf) {
+				t.Errorf("normalizeQueueServerConfig() config = %+v, not coerced as expected", cnf)
+			}
+		})
+	}
+}
+
+func TestNormalizeSchedulerOpts(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []SchedulerServerOption
+		check   func(opts asynq.SchedulerOpts) bool
+		wantErr error
+	}{
+		{
+			name:  "location",
+			opts:  []SchedulerServerOption{WithSchedulerLocation("Europe/Berlin")},
+			check: func(opts asynq.SchedulerOpts) bool { return opts.Location.String() == "Europe/Berlin" },
+		},
+		{
+			name:    "unknown location",
+			opts:    []SchedulerServerOption{WithSchedulerLocation("Mars/Olympus")},
+			check:   func(opts asynq.SchedulerOpts) bool { return opts.Location == time.UTC },
+			wantErr: ErrUnknownTimeZone,
+		},
+		{
+			name:    "unknown log level",
+			opts:    []SchedulerServerOption{WithSchedulerLogLevel("loud")},
+			check:   func(opts asynq.SchedulerOpts) bool { return opts.LogLevel == asynq.InfoLevel },
+			wantErr: ErrInvalidSchedulerOption,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := asynq.SchedulerOpts{Location: time.UTC, LogLevel: asynq.ErrorLevel}
+			for _, opt := range tt.opts {
+				opt(&opts)
+			}
+
+			err := normalizeSchedulerOpts(&opts)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalizeSchedulerOpts() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.check(opts) {
+				t.Errorf("normalizeSchedulerOpts() options = %+v, not coerced as expected", opts)
+			}
+		})
+	}
+}
+
+func TestStrictServers(t *testing.T) {
+	// The servers fail before connecting to redis, so it doesn't have to be reachable.
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+
+	t.Run("queue server", func(t *testing.T) {
+		srv := NewQueueServer(rdb, WithQueueConcurrency(0))
+		srv.SetStrictOptions(true)
+
+		err := srv.Run(HandlerFunc("test:task", func(_ context.Context, _ any) error { return nil }, Timeout(0)))()
+		if !errors.Is(err, ErrInvalidQueueServerOption) || !errors.Is(err, ErrInvalidTaskOption) {
+			t.Errorf("Run() error = %v, want the server and handler options reported", err)
+		}
+	})
+
+	t.Run("scheduler server", func(t *testing.T) {
+		srv := NewSchedulerServer(rdb, WithSchedulerLocation("Mars/Olympus"))
+		srv.SetStrictOptions(true)
+
+		if err := srv.ScheduleTask("@every 1m", "test:task", Unique(0)); !errors.Is(err, ErrInvalidTaskOption) {
+			t.Errorf("ScheduleTask() error = %v, want ErrInvalidTaskOption", err)
+		}
+		if err := srv.Run()(); !errors.Is(err, ErrInvalidSchedulerOption) {
+			t.Errorf("Run() error = %v, want ErrInvalidSchedulerOption", err)
+		}
+	})
+}
@@ -3,7 +3,10 @@ package asyncer
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -12,19 +15,31 @@ import (
 )
 
 // Default queue options.
-// It must be global to keep consistency with the enqueuer.go file.
-var (
-	workerConcurrency     = 1                // Default worker concurrency
-	workerShutdownTimeout = time.Second * 10 // Default worker shutdown timeout
-	workerLogLevel        = "info"           // Default worker log level
-	queueName             = "default"        // Default queue name
-	queuePriority         = 1                // Default queue priority
+// They are constants, so every queue server and enqueuer starts from the same defaults
+// and options applied to one of them never leak into another.
+const (
+	defaultWorkerShutdownTimeout = time.Second * 10 // Default worker shutdown timeout
+	defaultWorkerLogLevel        = LogLevelInfo     // Default worker log level
+	defaultQueueName             = "default"        // Default queue name
+	defaultQueuePriority         = 1                // Default queue priority
 )
 
 type (
 	// QueueServer is a wrapper for asynq.Server.
 	QueueServer struct {
-		asynq *asynq.Server
+		mu      sync.Mutex
+		asynq   *asynq.Server
+		cnf     asynq.Config
+		mux     *asynq.ServeMux
+		client  *asynq.Client
+		redis   redis.UniversalClient
+		stats   *handlerStats
+		workers int                  // configured concurrency, the upper bound of autoscaling
+		strict  bool                 // report invalid options instead of coercing them
+		optsErr error                // invalid server options, reported in strict mode
+		history scheduleHistoryTasks // names of the tasks scheduled with the history enabled
+		done    chan struct{}
+		once    sync.Once
 	}
 
 	// QueueServerOption is a function that configures a QueueServer.
@@ -35,35 +50,52 @@ type (
 // It takes a redis connection option and optional queue server options.
 // The function returns a pointer to the created QueueServer.
 func NewQueueServer(redisClient redis.UniversalClient, opts ...QueueServerOption) *QueueServer {
-	// Get the number of available CPUs.
-	// Use half of the CPUs for the worker concurrency.
-	useProcs := runtime.GOMAXPROCS(0)
-	if useProcs == 0 {
-		useProcs = 1
-	} else if useProcs > 1 {
-		useProcs = useProcs / 2
-	}
-
-	// Default worker concurrency
-	workerConcurrency = useProcs
-
 	// Init default queue server config.
 	// It can be changed by the options.
-	cnf := asynq.Config{
-		Concurrency:     workerConcurrency,
-		LogLevel:        castToAsynqLogLevel(workerLogLevel),
-		ShutdownTimeout: workerShutdownTimeout,
-		Queues: map[string]int{
-			queueName: queuePriority,
-		},
-	}
+	cnf := defaultQueueServerConfig()
 
 	// Apply options
 	for _, opt := range opts {
 		opt(&cnf)
 	}
+	optsErr := normalizeQueueServerConfig(&cnf)
+
+	// Tasks requeued by handler options (e.g. MaxConcurrency) must not consume retries.
+	withRequeueSupport(&cnf)
+
+	return &QueueServer{
+		asynq:   asynq.NewServerFromRedisClient(redisClient, cnf),
+		cnf:     cnf,
+		client:  asynq.NewClientFromRedisClient(redisClient),
+		redis:   redisClient,
+		stats:   &handlerStats{},
+		workers: cnf.Concurrency,
+		optsErr: optsErr,
+		done:    make(chan struct{}),
+	}
+}
+
+// defaultQueueServerConfig returns a new queue server config with the default values.
+// Every call returns a fresh copy, so the config can be safely modified by the options.
+func defaultQueueServerConfig() asynq.Config {
+	return asynq.Config{
+		Concurrency:     defaultWorkerConcurrency(),
+		LogLevel:        castToAsynqLogLevel(defaultWorkerLogLevel),
+		ShutdownTimeout: defaultWorkerShutdownTimeout,
+		Queues: map[string]int{
+			defaultQueueName: defaultQueuePriority,
+		},
+	}
+}
 
-	return &QueueServer{asynq: asynq.NewServerFromRedisClient(redisClient, cnf)}
+// defaultWorkerConcurrency returns the default worker concurrency.
+// It uses half of the available CPUs.
+func defaultWorkerConcurrency() int {
+	useProcs := runtime.GOMAXPROCS(0)
+	if useProcs > 1 {
+		useProcs = useProcs / 2
+	}
+	return max(useProcs, 1)
 }
 
 // Run starts the queue server and registers the provided task handlers.
@@ -77,34 +109,172 @@ func NewQueueServer(redisClient redis.UniversalClient, opts ...QueueServerOption
 //	))
 //
 // The function returns an error if the server fails to start.
+// In strict mode (see SetStrictOptions), it also returns an error if any server or handler option is invalid.
+// The server runs until it receives a termination signal or Shutdown is called.
 func (srv *QueueServer) Run(handlers ...TaskHandler) func() error {
 	return func() error {
+		if err := srv.validateOptions(handlers); err != nil {
+			return errors.Join(ErrFailedToStartQueueServer, err)
+		}
+
 		mux := asynq.NewServeMux()
 
 		// Register handlers
 		for _, h := range handlers {
-			handlerFunc := func(
-				fn func(ctx context.Context, payload []byte) error,
-			) func(ctx context.Context, t *asynq.Task) error {
-				return func(ctx context.Context, t *asynq.Task) error {
-					return fn(ctx, t.Payload())
-				}
-			}
-			mux.HandleFunc(h.TaskName(), handlerFunc(h.Handle))
+			mux.Handle(h.TaskName(), srv.handler(h))
 		}
 
-		// Run server
-		if err := srv.asynq.Run(mux); err != nil {
+		// Start server
+		srv.mu.Lock()
+		srv.mux = mux
+		err := srv.asynq.Start(mux)
+		srv.mu.Unlock()
+		if err != nil {
 			return errors.Join(ErrFailedToStartQueueServer, err)
 		}
 
+		// Wait for a termination signal or shutdown
+		waitForSignals(srv.done, srv.stop)
+		srv.Shutdown()
+
+		return nil
+	}
+}
+
+// SetStrictOptions enables or disables the strict mode of the server.
+// By default, invalid server and handler options are coerced to the closest valid values (see ValidateTaskOptions).
+// In strict mode, the server fails to run with an error describing all invalid options instead,
+// and UpdateQueues rejects invalid queues. It must be called before Run, e.g.:
+//
+//	queueServer := asyncer.NewQueueServer(redisClient, asyncer.WithQueueConcurrency(n))
+//	queueServer.SetStrictOptions(true)
+func (srv *QueueServer) SetStrictOptions(strict bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.strict = strict
+}
+
+// validateOptions returns an error describing all invalid server and handler options in strict mode.
+func (srv *QueueServer) validateOptions(handlers []TaskHandler) error {
+	srv.mu.Lock()
+	strict, errs := srv.strict, []error{srv.optsErr}
+	srv.mu.Unlock()
+
+	if !strict {
+		return nil
+	}
+	for _, h := range handlers {
+		if _, err := normalizeOptions(h.Options(), true); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.TaskName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UpdateQueues changes the queues with their priorities and the strict priority mode of the running server.
+// The server is replaced with a new one using the updated configuration:
+// the new server starts processing, then the current one stops fetching new tasks
+// and its in-flight tasks are finished in the background.
+// If the new server fails to start, the current one keeps running.
+// Empty queues map keeps the current queues, queues with an empty name or a non-positive priority are ignored.
+// In strict mode (see SetStrictOptions), invalid queues are rejected with an error and the server is kept as is.
+func (srv *QueueServer) UpdateQueues(queues map[string]int, strictPriority bool) error {
+	return srv.reconfigure(func(cnf *asynq.Config) {
+		WithQueues(queues)(cnf)
+		WithQueueStrictPriority(strictPriority)(cnf)
+	})
+}
+
+// reconfigure replaces the underlying asynq server with a new one, configured by the given function.
+// If the server is running, the new one is started with the same handlers.
+// In strict mode, the server is kept as is if the configuration is invalid.
+func (srv *QueueServer) reconfigure(fn func(*asynq.Config)) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	// Do not start a new server after shutdown.
+	select {
+	case <-srv.done:
 		return nil
+	default:
+	}
+
+	cnf := srv.cnf
+	cnf.Queues = maps.Clone(srv.cnf.Queues)
+	fn(&cnf)
+	if err := normalizeQueueServerConfig(&cnf); err != nil && srv.strict {
+		return err
+	}
+	next := asynq.NewServerFromRedisClient(srv.redis, cnf)
+
+	if srv.mux != nil {
+		// Start the new server first, so the current one keeps processing if it fails to start.
+		if err := next.Start(srv.mux); err != nil {
+			return errors.Join(ErrFailedToStartQueueServer, err)
+		}
+		prev := srv.asynq
+		prev.Stop()
+		go prev.Shutdown()
+	}
+
+	srv.asynq = next
+	srv.cnf = cnf
+
+	return nil
+}
+
+// stop stops the server from fetching new tasks.
+// The in-flight tasks are processed as usual.
+func (srv *QueueServer) stop() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.asynq.Stop()
+}
+
+// handler returns the asynq handler for the given task handler.
+// Handler options (e.g. TenantConcurrency, MaxConcurrency, RateLimit) are applied as middlewares.
+// The concurrency limits are checked first, so a task requeued for lack of a free slot doesn't consume the rate limit.
+// Invalid handler options are coerced to the closest valid values (see ValidateTaskOptions).
+func (srv *QueueServer) handler(h TaskHandler) asynq.Handler {
+	opts, _ := normalizeOptions(h.Options(), false)
+
+	var next asynq.Handler = asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		return h.Handle(ctx, t.Payload())
+	})
+	next = srv.scheduleHistory(next)
+	next = srv.releaseUniqueLock(next)
+
+	// The fair share is charged right before the handler, so only the processed tasks count.
+	if opt, ok := findOption[tenantFairnessOption](opts); ok {
+		next = srv.tenantFairness(h.TaskName(), opt, next)
 	}
+
+	if opt, ok := findOption[rateLimitOption](opts); ok {
+		next = srv.rateLimit(rateLimitKey(h.TaskName()), opt.limit, opt.window, next)
+	}
+	if opt, ok := findOption[concurrencyLimitOption](opts); ok {
+		next = srv.concurrencyLimit(concurrencyLimitKey(h.TaskName()), opt.limit, next)
+	}
+	if opt, ok := findOption[tenantConcurrencyOption](opts); ok {
+		next = srv.tenantConcurrencyLimit(h.TaskName(), opt, next)
+	}
+
+	// The outcomes are recorded for every task taken by a worker, including the requeued ones.
+	next = srv.handlerStatsCollector(next)
+
+	return next
 }
 
 // Shutdown gracefully shuts down the queue server by waiting for all
 // in-flight tasks to finish processing before shutdown.
 func (srv *QueueServer) Shutdown() {
+	srv.once.Do(func() { close(srv.done) })
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
 	srv.asynq.Stop()
 	srv.asynq.Shutdown()
 }
@@ -0,0 +1,146 @@
+package asyncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// QueueConfig is the runtime queue configuration shared by queue servers via redis.
+type QueueConfig struct {
+	// Queues is a map of queue names to their priorities.
+	Queues map[string]int `json:"queues"`
+	// StrictPriority indicates whether the queue priority should be treated strictly.
+	StrictPriority bool `json:"strict_priority"`
+}
+
+// SetQueueConfig stores the queue configuration in redis under the given name
+// and notifies all queue servers watching it (see QueueServer.WatchQueueConfig).
+func SetQueueConfig(ctx context.Context, redisClient redis.UniversalClient, name string, cfg QueueConfig) error {
+	if len(cfg.Queues) == 0 {
+		return errors.Join(ErrFailedToSetQueueConfig, ErrQueuesAreEmpty)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return errors.Join(ErrFailedToSetQueueConfig, err)
+	}
+
+	if _, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, queueConfigKey(name), data, 0)
+		pipe.Publish(ctx, queueConfigChannel(name), data)
+		return nil
+	}); err != nil {
+		return errors.Join(ErrFailedToSetQueueConfig, err)
+	}
+
+	return nil
+}
+
+// GetQueueConfig returns the queue configuration stored in redis under the given name.
+// It returns ErrQueueConfigNotFound if there is no such configuration.
+func GetQueueConfig(ctx context.Context, redisClient redis.UniversalClient, name string) (QueueConfig, error) {
+	data, err := redisClient.Get(ctx, queueConfigKey(name)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return QueueConfig{}, ErrQueueConfigNotFound
+		}
+		return QueueConfig{}, err
+	}
+
+	var cfg QueueConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return QueueConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// WatchQueueConfig applies the queue configuration stored in redis under the given name
+// to the running server, and keeps applying its updates until the context is canceled.
+// It returns a function that can be used to run the watcher in an error group.
+// E.g.:
+//
+//	eg, ctx := errgroup.WithContext(context.Background())
+//	eg.Go(queueServer.Run(handlers...))
+//	eg.Go(queueServer.WatchQueueConfig(ctx, "workers"))
+//
+//	// Somewhere else, e.g. in an admin API:
+//	asyncer.SetQueueConfig(ctx, redisClient, "workers", asyncer.QueueConfig{
+//		Queues: map[string]int{"critical": 10, "default": 1},
+//	})
+func (srv *QueueServer) WatchQueueConfig(ctx context.Context, name string) func() error {
+	return func() error {
+		// Subscribe before loading the current configuration, so no update is missed.
+		pubsub := srv.redis.Subscribe(ctx, queueConfigChannel(name))
+		defer pubsub.Close()
+
+		cfg, err := GetQueueConfig(ctx, srv.redis, name)
+		switch {
+		case err == nil:
+			if err := srv.applyQueueConfig(cfg); err != nil {
+				return err
+			}
+		case !errors.Is(err, ErrQueueConfigNotFound):
+			return errors.Join(ErrFailedToWatchQueueConfig, err)
+		}
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-srv.done:
+				return nil
+			case msg, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				var cfg QueueConfig
+				if err := json.Unmarshal([]byte(msg.Payload), &cfg); err != nil {
+					return errors.Join(ErrFailedToWatchQueueConfig, err)
+				}
+				if err := srv.applyQueueConfig(cfg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// applyQueueConfig updates the server queues if the configuration differs from the current one.
+func (srv *QueueServer) applyQueueConfig(cfg QueueConfig) error {
+	if len(cfg.Queues) == 0 || !srv.queuesChanged(cfg) {
+		return nil
+	}
+	return srv.UpdateQueues(cfg.Queues, cfg.StrictPriority)
+}
+
+// queuesChanged reports whether the configuration differs from the current server configuration.
+func (srv *QueueServer) queuesChanged(cfg QueueConfig) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.cnf.StrictPriority != cfg.StrictPriority || len(srv.cnf.Queues) != len(cfg.Queues) {
+		return true
+	}
+	for name, priority := range cfg.Queues {
+		if current, ok := srv.cnf.Queues[name]; !ok || current != priority {
+			return true
+		}
+	}
+	return false
+}
+
+// queueConfigKey returns the redis key of the queue configuration.
+func queueConfigKey(name string) string {
+	return fmt.Sprintf("asyncer:queue-config:%s", name)
+}
+
+// queueConfigChannel returns the redis channel of the queue configuration updates.
+func queueConfigChannel(name string) string {
+	return fmt.Sprintf("asyncer:queue-config:%s:updates", name)
+}
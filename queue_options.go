@@ -1,6 +1,7 @@
 package asyncer
 
 import (
+	"maps"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -9,20 +10,20 @@ import (
 // WithQueues sets the queues with their priorities.
 // The map key is the queue name and the value is the priority.
 // Higher priority values give the queue higher processing preference.
+// Queues with an empty name or a non-positive priority are ignored, an empty map keeps the current queues.
+// The map is copied, so changing it afterwards doesn't affect the server.
 func WithQueues(queues map[string]int) QueueServerOption {
 	return func(cnf *asynq.Config) {
 		if len(queues) > 0 {
-			cnf.Queues = queues
+			cnf.Queues = maps.Clone(queues)
 		}
 	}
 }
 
 // WithQueue sets the queue name.
+// A non-positive priority falls back to 1.
 func WithQueue(name string, priority int) QueueServerOption {
 	return func(cnf *asynq.Config) {
-		if priority < 1 {
-			priority = 1
-		}
 		cnf.Queues = map[string]int{
 			name: priority,
 		}
@@ -30,29 +31,26 @@ func WithQueue(name string, priority int) QueueServerOption {
 }
 
 // WithQueueConcurrency sets the queue concurrency.
+// A non-positive concurrency falls back to 1.
 func WithQueueConcurrency(concurrency int) QueueServerOption {
 	return func(cnf *asynq.Config) {
-		if concurrency < 1 {
-			concurrency = 1
-		}
 		cnf.Concurrency = concurrency
 	}
 }
 
 // WithQueueShutdownTimeout sets the queue shutdown timeout.
+// A negative timeout falls back to zero.
 func WithQueueShutdownTimeout(timeout time.Duration) QueueServerOption {
 	return func(cnf *asynq.Config) {
-		if timeout < 0 {
-			timeout = 0
-		}
 		cnf.ShutdownTimeout = timeout
 	}
 }
 
 // WithQueueLogLevel sets the queue log level.
+// Unknown levels fall back to the info level.
 func WithQueueLogLevel(level string) QueueServerOption {
 	return func(cnf *asynq.Config) {
-		cnf.LogLevel = castToAsynqLogLevel(level)
+		cnf.LogLevel = asynqLogLevel(level)
 	}
 }
 
@@ -80,3 +78,44 @@ func WithQueueErrorHandler(handler asynq.ErrorHandler) QueueServerOption {
 		}
 	}
 }
+
+// WithQueueGroupAggregator sets the function used to aggregate tasks of a group into one task.
+// Group aggregation is disabled on the server unless an aggregator is set.
+func WithQueueGroupAggregator(aggregator asynq.GroupAggregator) QueueServerOption {
+	return func(cnf *asynq.Config) {
+		if aggregator != nil {
+			cnf.GroupAggregator = aggregator
+		}
+	}
+}
+
+// WithQueueBatchAggregator enables group aggregation with the batch aggregator.
+// The batch aggregator combines the payloads of a group into a JSON array wrapped into an object,
+// e.g. {"asyncer:batch":[...]}, which can be handled by a handler created with BatchHandlerFunc.
+func WithQueueBatchAggregator() QueueServerOption {
+	return WithQueueGroupAggregator(asynq.GroupAggregatorFunc(aggregateBatch))
+}
+
+// WithQueueGroupGracePeriod sets the time the server waits for an incoming task before aggregating a group.
+// Zero uses the default of one minute, the minimum grace period is one second and shorter ones fall back to it.
+func WithQueueGroupGracePeriod(d time.Duration) QueueServerOption {
+	return func(cnf *asynq.Config) {
+		cnf.GroupGracePeriod = d
+	}
+}
+
+// WithQueueGroupMaxDelay sets the maximum time the server waits for incoming tasks before aggregating a group.
+// Zero means no delay limit, a negative delay falls back to it.
+func WithQueueGroupMaxDelay(d time.Duration) QueueServerOption {
+	return func(cnf *asynq.Config) {
+		cnf.GroupMaxDelay = d
+	}
+}
+
+// WithQueueGroupMaxSize sets the maximum number of tasks aggregated into a single task.
+// Zero means no size limit, a negative size falls back to it.
+func WithQueueGroupMaxSize(size int) QueueServerOption {
+	return func(cnf *asynq.Config) {
+		cnf.GroupMaxSize = size
+	}
+}
@@ -0,0 +1,103 @@
+package asyncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// rateLimitOption is a handler option that limits
+	// the number of processed tasks per time window across all queue servers.
+	// It is handled by the queue server and ignored by asynq.
+	rateLimitOption struct {
+		limit  int
+		window time.Duration
+	}
+)
+
+// rateLimitScript takes a slot of the redis sliding window rate limiter.
+//
+// KEYS[1] -> rate limiter key
+// ARGV[1] -> current time in milliseconds
+// ARGV[2] -> window in milliseconds
+// ARGV[3] -> limit
+// ARGV[4] -> slot member
+//
+// Returns 0 if the slot is taken, otherwise the number of milliseconds
+// until the oldest slot in the window expires.
+var rateLimitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+if redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], window)
+	return 0
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local wait = tonumber(oldest[2]) + window - now
+if wait < 1 then
+	wait = 1
+end
+return wait
+`)
+
+// RateLimit limits the number of tasks of the handler processed within the given window
+// across all queue servers sharing the same redis, e.g. 100 tasks per second.
+// Tasks exceeding the limit are rescheduled to the moment a slot is freed, without consuming a retry.
+// A task with no retries left is enqueued again as a copy with a new ID instead, so it's not archived.
+// It's a handler option, e.g.:
+//
+//	asyncer.HandlerFunc("email:send", sendEmailHandler, asyncer.RateLimit(100, time.Second))
+func RateLimit(limit int, window time.Duration) TaskOption {
+	if limit < 1 || window <= 0 {
+		return invalid(
+			rateLimitOption{limit: max(limit, 1), window: max(window, time.Second)},
+			"rate limit must be positive, got %d per %v", limit, window,
+		)
+	}
+	return rateLimitOption{limit: limit, window: window}
+}
+
+// String returns the string representation of the option.
+func (o rateLimitOption) String() string { return fmt.Sprintf("RateLimit(%d, %v)", o.limit, o.window) }
+
+// Type returns the type of the option.
+func (o rateLimitOption) Type() asynq.OptionType { return rateLimitOpt }
+
+// Value returns the value of the option.
+func (o rateLimitOption) Value() any { return o.limit }
+
+// rateLimit returns a middleware which limits the number of processed tasks
+// sharing the given rate limiter key within the window.
+func (srv *QueueServer) rateLimit(key string, limit int, window time.Duration, next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		taskID, ok := asynq.GetTaskID(ctx)
+		if !ok {
+			return ErrMissedTaskID
+		}
+
+		now := time.Now()
+		wait, err := rateLimitScript.Run(ctx, srv.redis,
+			[]string{key},
+			now.UnixMilli(), window.Milliseconds(), limit, fmt.Sprintf("%s:%d", taskID, now.UnixNano()),
+		).Int64()
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return srv.requeue(ctx, t, time.Duration(wait)*time.Millisecond, ErrRateLimitExceeded)
+		}
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// rateLimitKey returns the redis key of the task rate limiter.
+func rateLimitKey(taskName string) string {
+	return fmt.Sprintf("asyncer:ratelimit:%s", taskName)
+}
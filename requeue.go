@@ -0,0 +1,131 @@
+package asyncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// defaultRequeueDelay is the default delay before a requeued task is processed again.
+const defaultRequeueDelay = time.Second
+
+// RequeueError is returned by a task handler to reschedule the task without consuming a retry.
+// The queue server doesn't count such tasks as failed and doesn't pass the error to the error handler.
+// Note that asynq archives a task with no retries left regardless of the error.
+type RequeueError struct {
+	// RetryIn is the delay before the task is processed again.
+	RetryIn time.Duration
+	// Err is the reason why the task was requeued.
+	Err error
+}
+
+// Error returns the error message.
+func (e *RequeueError) Error() string {
+	return fmt.Sprintf("task requeued, retry in %v: %v", e.RetryIn, e.Err)
+}
+
+// Unwrap returns the reason why the task was requeued.
+func (e *RequeueError) Unwrap() error {
+	return e.Err
+}
+
+// IsRequeueError reports whether the error requests to reschedule the task.
+func IsRequeueError(err error) bool {
+	var rerr *RequeueError
+	return errors.As(err, &rerr)
+}
+
+// requeue reschedules the task without consuming a retry.
+// While the task has retries left, it returns the error which makes asynq retry the task after the delay,
+// so the task keeps its ID and options, while asynq records the reason as the last error of the task.
+// asynq archives a task whose retries are exhausted regardless of the error (e.g. every task enqueued with MaxRetry(0)),
+// so such a task is enqueued again as a copy with the same options and a new ID, and the original one is done.
+// A small jitter is added to the delay to spread requeued tasks over time.
+func (srv *QueueServer) requeue(ctx context.Context, t *asynq.Task, retryIn time.Duration, reason error) error {
+	if retryIn <= 0 {
+		retryIn = defaultRequeueDelay
+	}
+	retryIn += rand.N(retryIn / 2)
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if retried < maxRetry {
+		return &RequeueError{RetryIn: retryIn, Err: reason}
+	}
+
+	queue, _ := asynq.GetQueueName(ctx)
+	info := &asynq.TaskInfo{Queue: queue, MaxRetry: maxRetry}
+	if taskID, ok := asynq.GetTaskID(ctx); ok {
+		// The other options are only known to asynq, the queue and max retry are kept if they can't be read.
+		if i, err := asynq.NewInspectorFromRedisClient(srv.redis).GetTaskInfo(queue, taskID); err == nil {
+			info = i
+		}
+	}
+	if _, err := srv.client.EnqueueContext(ctx, asynq.NewTask(t.Type(), t.Payload()), requeueOptions(info, retryIn)...); err != nil {
+		return errors.Join(reason, err)
+	}
+
+	return nil
+}
+
+// requeueOptions returns the options of the copy of the task requeued after the delay.
+// The uniqueness of the task is not kept, asynq doesn't expose it.
+func requeueOptions(info *asynq.TaskInfo, retryIn time.Duration) []asynq.Option {
+	opts := []asynq.Option{
+		asynq.Queue(info.Queue),
+		asynq.MaxRetry(info.MaxRetry),
+		asynq.ProcessIn(retryIn),
+	}
+	if info.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(info.Timeout))
+	}
+	if !info.Deadline.IsZero() {
+		opts = append(opts, asynq.Deadline(info.Deadline))
+	}
+	if info.Retention > 0 {
+		opts = append(opts, asynq.Retention(info.Retention))
+	}
+	if info.Group != "" {
+		opts = append(opts, asynq.Group(info.Group))
+	}
+	return opts
+}
+
+// withRequeueSupport wraps the server config functions,
+// so tasks rescheduled with RequeueError don't consume retries and use the requested delay.
+func withRequeueSupport(cnf *asynq.Config) {
+	isFailure := cnf.IsFailure
+	cnf.IsFailure = func(err error) bool {
+		if IsRequeueError(err) {
+			return false
+		}
+		if isFailure != nil {
+			return isFailure(err)
+		}
+		return true
+	}
+
+	retryDelay := cnf.RetryDelayFunc
+	if retryDelay == nil {
+		retryDelay = asynq.DefaultRetryDelayFunc
+	}
+	cnf.RetryDelayFunc = func(n int, err error, t *asynq.Task) time.Duration {
+		var rerr *RequeueError
+		if errors.As(err, &rerr) {
+			return rerr.RetryIn
+		}
+		return retryDelay(n, err, t)
+	}
+
+	if errHandler := cnf.ErrorHandler; errHandler != nil {
+		cnf.ErrorHandler = asynq.ErrorHandlerFunc(func(ctx context.Context, t *asynq.Task, err error) {
+			if !IsRequeueError(err) {
+				errHandler.HandleError(ctx, t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package asyncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRequeueOptions(t *testing.T) {
+	deadline := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		info *asynq.TaskInfo
+		want []string
+	}{
+		{
+			name: "task without retries",
+			info: &asynq.TaskInfo{Queue: "default", MaxRetry: 0},
+			want: []string{`Queue("default")`, "MaxRetry(0)"},
+		},
+		{
+			name: "task with all options",
+			info: &asynq.TaskInfo{
+				Queue:     "critical",
+				MaxRetry:  3,
+				Timeout:   time.Minute,
+				Deadline:  deadline,
+				Retention: time.Hour,
+				Group:     "user:42",
+			},
+			want: []string{
+				`Queue("critical")`,
+				"MaxRetry(3)",
+				"Timeout(1m0s)",
+				asynq.Deadline(deadline).String(),
+				"Retention(1h0m0s)",
+				`Group("user:42")`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := requeueOptions(tt.info, time.Second)
+
+			got := make(map[string]bool, len(opts))
+			var processIn time.Duration
+			for _, opt := range opts {
+				got[opt.String()] = true
+				if opt.Type() == asynq.ProcessInOpt {
+					processIn, _ = opt.Value().(time.Duration)
+				}
+			}
+			for _, want := range tt.want {
+				if !got[want] {
+					t.Errorf("requeueOptions() = %v, missing %s", opts, want)
+				}
+			}
+			if processIn != time.Second {
+				t.Errorf("requeueOptions() process in = %v, want %v", processIn, time.Second)
+			}
+		})
+	}
+}
+
+func TestRequeueWithoutRetriesLeft(t *testing.T) {
+	// The task context carries no retry count and max retry, as a task enqueued with MaxRetry(0).
+	// asynq would archive the task on the requeue error, so a copy is enqueued instead,
+	// which fails here because redis is unreachable.
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+	srv := &QueueServer{client: asynq.NewClientFromRedisClient(rdb), redis: rdb}
+
+	err := srv.requeue(context.Background(), asynq.NewTask("email:send", nil), time.Second, ErrRateLimitExceeded)
+	if IsRequeueError(err) {
+		t.Fatalf("requeue() = %v, want the copy to be enqueued instead of a requeue error", err)
+	}
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("requeue() = %v, want it to wrap the reason", err)
+	}
+}
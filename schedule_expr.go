@@ -0,0 +1,474 @@
+package asyncer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Extended schedule syntax prefixes.
+const (
+	atSpecPrefix    = "@at "   // One-off run at an absolute time
+	rruleSpecPrefix = "RRULE:" // iCalendar recurrence rule
+)
+
+// maxRRuleYears is the number of years a recurrence rule is searched for the next run.
+// It covers the 28-year cycle of the calendar, so a rule without runs in it never fires.
+const maxRRuleYears = 28
+
+// Recurrence rule frequencies.
+const (
+	rruleDaily rruleFreq = iota
+	rruleWeekly
+	rruleMonthly
+	rruleYearly
+)
+
+// atLayouts are the accepted layouts of the @at times, besides RFC 3339.
+var atLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// rruleWeekdays are the iCalendar weekday names.
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+type (
+	// atSchedule is a schedule firing once, at the given time.
+	atSchedule struct {
+		wall     time.Time      // date and clock of the run, as parsed
+		location *time.Location // time zone of the wall clock, nil to use the location of the evaluated time
+		absolute bool           // the time has an explicit UTC offset, the wall clock is ignored
+	}
+
+	// rruleFreq is the frequency of a recurrence rule.
+	rruleFreq int
+
+	// rruleWeekday is a weekday of a recurrence rule with an optional ordinal, e.g. -1FR.
+	rruleWeekday struct {
+		day time.Weekday
+		n   int // nth weekday of the month or year, negative from the end, zero for every one
+	}
+
+	// rruleSchedule is a schedule firing on the occurrences of a recurrence rule.
+	rruleSchedule struct {
+		freq      rruleFreq
+		months    []int
+		monthDays []int
+		weekdays  []rruleWeekday
+		setPos    []int
+		hours     []int
+		minutes   []int
+		seconds   []int
+		until     *atSchedule    // last possible run, nil for no limit
+		location  *time.Location // nil to use the location of the evaluated time
+	}
+)
+
+// parseExtendedSpec parses the schedule expressions besides cron:
+//
+//   - "@at 2026-12-01 09:00 Europe/Berlin" fires once, at the given time.
+//     The time zone name is optional, an RFC 3339 time like "@at 2026-12-01T09:00:00+01:00" is accepted as well.
+//   - "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;BYHOUR=9" fires on the occurrences of the
+//     iCalendar recurrence rule (RFC 5545), e.g. at 9am on the last business day of the month.
+//
+// Both accept the CRON_TZ= prefix, the same as cron specs.
+// It reports false if the spec is not an extended one.
+func parseExtendedSpec(spec string) (cron.Schedule, bool, error) {
+	expr := strings.TrimSpace(spec)
+	zone := cronSpecTimeZone(expr)
+	if zone != "" {
+		_, expr, _ = strings.Cut(expr, " ")
+		expr = strings.TrimSpace(expr)
+	}
+
+	isAt := strings.HasPrefix(expr, atSpecPrefix)
+	isRRule := len(expr) >= len(rruleSpecPrefix) && strings.EqualFold(expr[:len(rruleSpecPrefix)], rruleSpecPrefix)
+	if !isAt && !isRRule {
+		return nil, false, nil
+	}
+
+	var loc *time.Location
+	if zone != "" {
+		var err error
+		if loc, err = loadLocation(zone); err != nil {
+			return nil, true, err
+		}
+	}
+
+	if isAt {
+		s, err := parseAtSpec(strings.TrimSpace(expr[len(atSpecPrefix):]), loc)
+		return s, true, err
+	}
+	s, err := parseRRuleSpec(expr[len(rruleSpecPrefix):], loc)
+	return s, true, err
+}
+
+// parseAtSpec parses the time of an @at spec, with an optional trailing time zone name.
+func parseAtSpec(value string, loc *time.Location) (*atSchedule, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &atSchedule{wall: t, absolute: true}, nil
+	}
+	if wall, ok := parseAtWall(value); ok {
+		return &atSchedule{wall: wall, location: loc}, nil
+	}
+
+	// The last field may be a time zone name.
+	i := strings.LastIndexByte(value, ' ')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid time %q", value)
+	}
+	wall, ok := parseAtWall(strings.TrimSpace(value[:i]))
+	if !ok {
+		return nil, fmt.Errorf("invalid time %q", value)
+	}
+	zoneLoc, err := loadLocation(value[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	return &atSchedule{wall: wall, location: zoneLoc}, nil
+}
+
+// parseAtWall parses the date and clock of an @at time without a time zone.
+func parseAtWall(value string) (time.Time, bool) {
+	for _, layout := range atLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Next returns the time of the run if it's after the given time, or the zero time otherwise.
+func (s *atSchedule) Next(t time.Time) time.Time {
+	at := s.time(t.Location())
+	if !at.After(t) {
+		return time.Time{}
+	}
+	return at.In(t.Location())
+}
+
+// time returns the time of the run, evaluating the wall clock in the given location if the schedule has none.
+func (s *atSchedule) time(loc *time.Location) time.Time {
+	if s.absolute {
+		return s.wall
+	}
+	if s.location != nil {
+		loc = s.location
+	}
+	y, m, d := s.wall.Date()
+	h, mi, sec := s.wall.Clock()
+	return time.Date(y, m, d, h, mi, sec, 0, loc)
+}
+
+// parseRRuleSpec parses the supported subset of an iCalendar recurrence rule:
+// FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), BYMONTH, BYMONTHDAY, BYDAY, BYSETPOS,
+// BYHOUR, BYMINUTE, BYSECOND and UNTIL.
+// The scheduler keeps no run counter, so COUNT is not supported, neither is INTERVAL other than 1.
+// The runs are at midnight unless BYHOUR, BYMINUTE or BYSECOND are set.
+func parseRRuleSpec(rule string, loc *time.Location) (*rruleSchedule, error) {
+	s := &rruleSchedule{freq: -1, location: loc}
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			s.freq, err = parseRRuleFreq(value)
+		case "INTERVAL":
+			if value != "1" {
+				err = fmt.Errorf("INTERVAL=%s is not supported", value)
+			}
+		case "WKST":
+			if !strings.EqualFold(value, "MO") {
+				err = fmt.Errorf("WKST=%s is not supported, weeks start on Monday", value)
+			}
+		case "BYMONTH":
+			s.months, err = parseRRuleInts(value, 1, 12, false)
+		case "BYMONTHDAY":
+			s.monthDays, err = parseRRuleInts(value, 1, 31, true)
+		case "BYDAY":
+			s.weekdays, err = parseRRuleWeekdays(value)
+		case "BYSETPOS":
+			s.setPos, err = parseRRuleInts(value, 1, 366, true)
+		case "BYHOUR":
+			s.hours, err = parseRRuleInts(value, 0, 23, false)
+		case "BYMINUTE":
+			s.minutes, err = parseRRuleInts(value, 0, 59, false)
+		case "BYSECOND":
+			s.seconds, err = parseRRuleInts(value, 0, 59, false)
+		case "UNTIL":
+			s.until, err = parseRRuleUntil(value, loc)
+		default:
+			err = fmt.Errorf("%s is not supported", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", rule, err)
+		}
+	}
+
+	if err := s.normalize(); err != nil {
+		return nil, fmt.Errorf("invalid rule %q: %w", rule, err)
+	}
+	return s, nil
+}
+
+// normalize checks the rule and sets the defaults of the missing parts.
+func (s *rruleSchedule) normalize() error {
+	if s.freq < 0 {
+		return errors.New("FREQ is required")
+	}
+	for _, wd := range s.weekdays {
+		if wd.n != 0 && s.freq != rruleMonthly && s.freq != rruleYearly {
+			return errors.New("BYDAY ordinals are only supported with MONTHLY and YEARLY frequencies")
+		}
+	}
+
+	// Without day filters, the rule fires on the first day of the period.
+	switch s.freq {
+	case rruleWeekly:
+		if len(s.weekdays) == 0 {
+			return errors.New("BYDAY is required with WEEKLY frequency")
+		}
+	case rruleMonthly:
+		if len(s.monthDays) == 0 && len(s.weekdays) == 0 {
+			s.monthDays = []int{1}
+		}
+	case rruleYearly:
+		if len(s.months) == 0 && len(s.monthDays) == 0 && len(s.weekdays) == 0 {
+			s.months = []int{1}
+		}
+		if len(s.monthDays) == 0 && len(s.weekdays) == 0 {
+			s.monthDays = []int{1}
+		}
+	}
+
+	for _, list := range []*[]int{&s.hours, &s.minutes, &s.seconds} {
+		if len(*list) == 0 {
+			*list = []int{0}
+		}
+	}
+	return nil
+}
+
+// parseRRuleFreq parses the FREQ value of a recurrence rule.
+func parseRRuleFreq(value string) (rruleFreq, error) {
+	switch strings.ToUpper(value) {
+	case "DAILY":
+		return rruleDaily, nil
+	case "WEEKLY":
+		return rruleWeekly, nil
+	case "MONTHLY":
+		return rruleMonthly, nil
+	case "YEARLY":
+		return rruleYearly, nil
+	}
+	return 0, fmt.Errorf("FREQ=%s is not supported", value)
+}
+
+// parseRRuleInts parses the comma-separated numbers of a recurrence rule part.
+// Negative numbers count from the end, if allowed, and zero is not allowed with them.
+func parseRRuleInts(value string, minValue, maxValue int, negative bool) ([]int, error) {
+	var res []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		abs := n
+		if negative && n < 0 {
+			abs = -n
+		}
+		if abs < minValue || abs > maxValue {
+			return nil, fmt.Errorf("number %d is out of range", n)
+		}
+		res = append(res, n)
+	}
+	slices.Sort(res)
+	return slices.Compact(res), nil
+}
+
+// parseRRuleWeekdays parses the BYDAY value of a recurrence rule, e.g. "MO,TU" or "-1FR".
+func parseRRuleWeekdays(value string) ([]rruleWeekday, error) {
+	var res []rruleWeekday
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToUpper(strings.TrimSpace(field))
+		if len(field) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", field)
+		}
+		day, ok := rruleWeekdays[field[len(field)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", field)
+		}
+		wd := rruleWeekday{day: day}
+		if ordinal := field[:len(field)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday %q", field)
+			}
+			wd.n = n
+		}
+		res = append(res, wd)
+	}
+	return res, nil
+}
+
+// parseRRuleUntil parses the UNTIL value of a recurrence rule: a UTC time ending with Z,
+// a local time, or a date, which includes the whole day.
+func parseRRuleUntil(value string, loc *time.Location) (*atSchedule, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return &atSchedule{wall: t, absolute: true}, nil
+	}
+	if t, err := time.Parse("20060102T150405", value); err == nil {
+		return &atSchedule{wall: t, location: loc}, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return &atSchedule{wall: t.Add(24*time.Hour - time.Second), location: loc}, nil
+	}
+	return nil, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+// Next returns the first occurrence of the rule after the given time,
+// or the zero time if there is none within the calendar cycle or before UNTIL.
+func (s *rruleSchedule) Next(t time.Time) time.Time {
+	loc := s.location
+	if loc == nil {
+		loc = t.Location()
+	}
+	local := t.In(loc)
+	horizon := local.AddDate(maxRRuleYears, 0, 0)
+
+	for period := s.periodStart(local); !period.After(horizon); period = s.nextPeriod(period) {
+		for _, occ := range s.occurrences(period) {
+			if s.until != nil && occ.After(s.until.time(loc)) {
+				return time.Time{}
+			}
+			if occ.After(t) {
+				return occ.In(t.Location())
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// periodStart returns the start of the period of the rule frequency containing the given time.
+// Weeks start on Monday.
+func (s *rruleSchedule) periodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch s.freq {
+	case rruleWeekly:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case rruleMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case rruleYearly:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// nextPeriod returns the start of the period following the one starting at the given time.
+func (s *rruleSchedule) nextPeriod(start time.Time) time.Time {
+	y, m, d := start.Date()
+	switch s.freq {
+	case rruleWeekly:
+		return time.Date(y, m, d+7, 0, 0, 0, 0, start.Location())
+	case rruleMonthly:
+		return time.Date(y, m+1, 1, 0, 0, 0, 0, start.Location())
+	case rruleYearly:
+		return time.Date(y+1, time.January, 1, 0, 0, 0, 0, start.Location())
+	}
+	return time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+}
+
+// occurrences returns the ordered occurrences of the rule in the period starting at the given time.
+func (s *rruleSchedule) occurrences(start time.Time) []time.Time {
+	end := s.nextPeriod(start)
+	var res []time.Time
+	for day := start; day.Before(end); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location()) {
+		if !s.matchesDay(day) {
+			continue
+		}
+		for _, h := range s.hours {
+			for _, mi := range s.minutes {
+				for _, sec := range s.seconds {
+					res = append(res, time.Date(day.Year(), day.Month(), day.Day(), h, mi, sec, 0, day.Location()))
+				}
+			}
+		}
+	}
+
+	if len(s.setPos) == 0 {
+		return res
+	}
+	selected := make([]time.Time, 0, len(s.setPos))
+	for _, pos := range s.setPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(res) + pos
+		}
+		if i >= 0 && i < len(res) {
+			selected = append(selected, res[i])
+		}
+	}
+	slices.SortFunc(selected, time.Time.Compare)
+	return slices.CompactFunc(selected, time.Time.Equal)
+}
+
+// matchesDay reports whether the day matches the BYMONTH, BYMONTHDAY and BYDAY parts of the rule.
+func (s *rruleSchedule) matchesDay(day time.Time) bool {
+	if len(s.months) > 0 && !slices.Contains(s.months, int(day.Month())) {
+		return false
+	}
+
+	monthLen := daysIn(day.Year(), day.Month())
+	if len(s.monthDays) > 0 && !slices.ContainsFunc(s.monthDays, func(md int) bool {
+		return md == day.Day() || md < 0 && monthLen+md+1 == day.Day()
+	}) {
+		return false
+	}
+
+	if len(s.weekdays) > 0 && !slices.ContainsFunc(s.weekdays, func(wd rruleWeekday) bool {
+		return wd.day == day.Weekday() && (wd.n == 0 || s.matchesOrdinal(day, wd.n))
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// matchesOrdinal reports whether the day is the nth of its weekday in the month,
+// or in the year for YEARLY rules without BYMONTH. Negative ordinals count from the end.
+func (s *rruleSchedule) matchesOrdinal(day time.Time, n int) bool {
+	pos, length := day.Day(), daysIn(day.Year(), day.Month())
+	if s.freq == rruleYearly && len(s.months) == 0 {
+		pos, length = day.YearDay(), time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	if n > 0 {
+		return (pos-1)/7+1 == n
+	}
+	return (length-pos)/7+1 == -n
+}
+
+// daysIn returns the number of days in the month of the year.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,192 @@
+package asyncer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExtendedSpec(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         string
+		wantExtended bool
+		wantErr      bool
+	}{
+		{name: "cron spec", spec: "0 9 * * *"},
+		{name: "descriptor", spec: "@daily"},
+		{name: "at", spec: "@at 2026-12-01 09:00", wantExtended: true},
+		{name: "at with time zone", spec: "@at 2026-12-01 09:00 Europe/Berlin", wantExtended: true},
+		{name: "at with CRON_TZ", spec: "CRON_TZ=Europe/Berlin @at 2026-12-01T09:00", wantExtended: true},
+		{name: "at RFC 3339", spec: "@at 2026-12-01T09:00:00+01:00", wantExtended: true},
+		{name: "at date", spec: "@at 2026-12-01", wantExtended: true},
+		{name: "at invalid time", spec: "@at tomorrow", wantExtended: true, wantErr: true},
+		{name: "at unknown time zone", spec: "@at 2026-12-01 09:00 Mars/Olympus", wantExtended: true, wantErr: true},
+		{name: "at unknown CRON_TZ", spec: "CRON_TZ=Mars/Olympus @at 2026-12-01 09:00", wantExtended: true, wantErr: true},
+		{name: "rule", spec: "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;BYHOUR=9", wantExtended: true},
+		{name: "lowercase rule", spec: "rrule:freq=daily;byhour=9", wantExtended: true},
+		{name: "rule with until", spec: "RRULE:FREQ=DAILY;UNTIL=20261231T235959Z", wantExtended: true},
+		{name: "rule without freq", spec: "RRULE:BYHOUR=9", wantExtended: true, wantErr: true},
+		{name: "rule with unknown freq", spec: "RRULE:FREQ=HOURLY", wantExtended: true, wantErr: true},
+		{name: "rule with count", spec: "RRULE:FREQ=DAILY;COUNT=3", wantExtended: true, wantErr: true},
+		{name: "rule with interval", spec: "RRULE:FREQ=DAILY;INTERVAL=2", wantExtended: true, wantErr: true},
+		{name: "rule with interval 1", spec: "RRULE:FREQ=DAILY;INTERVAL=1", wantExtended: true},
+		{name: "rule with sunday week start", spec: "RRULE:FREQ=WEEKLY;BYDAY=MO;WKST=SU", wantExtended: true, wantErr: true},
+		{name: "weekly rule without days", spec: "RRULE:FREQ=WEEKLY", wantExtended: true, wantErr: true},
+		{name: "daily rule with ordinal", spec: "RRULE:FREQ=DAILY;BYDAY=1MO", wantExtended: true, wantErr: true},
+		{name: "rule with invalid weekday", spec: "RRULE:FREQ=WEEKLY;BYDAY=XX", wantExtended: true, wantErr: true},
+		{name: "rule with hour out of range", spec: "RRULE:FREQ=DAILY;BYHOUR=24", wantExtended: true, wantErr: true},
+		{name: "rule with negative hour", spec: "RRULE:FREQ=DAILY;BYHOUR=-1", wantExtended: true, wantErr: true},
+		{name: "rule with invalid until", spec: "RRULE:FREQ=DAILY;UNTIL=tomorrow", wantExtended: true, wantErr: true},
+		{name: "rule with empty part", spec: "RRULE:FREQ=DAILY;BYHOUR=", wantExtended: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, extended, err := parseExtendedSpec(tt.spec)
+			if extended != tt.wantExtended {
+				t.Errorf("parseExtendedSpec(%q) extended = %v, want %v", tt.spec, extended, tt.wantExtended)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseExtendedSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtendedScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) // Sunday
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "at",
+			spec: "@at 2026-12-01 09:00",
+			want: []time.Time{time.Date(2026, 12, 1, 9, 0, 0, 0, time.UTC), {}},
+		},
+		{
+			name: "at in the location of the evaluated time",
+			spec: "@at 2026-12-01 09:00",
+			from: from.In(berlin),
+			want: []time.Time{time.Date(2026, 12, 1, 9, 0, 0, 0, berlin), {}},
+		},
+		{
+			name: "at with time zone",
+			spec: "@at 2026-12-01 09:00 Europe/Berlin",
+			want: []time.Time{time.Date(2026, 12, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "at RFC 3339",
+			spec: "@at 2026-12-01T09:00:00+01:00",
+			want: []time.Time{time.Date(2026, 12, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "at in the past",
+			spec: "@at 2026-01-01 09:00",
+			want: []time.Time{{}},
+		},
+		{
+			name: "daily rule",
+			spec: "RRULE:FREQ=DAILY;BYHOUR=9,18;BYMINUTE=30",
+			want: []time.Time{
+				time.Date(2026, 10, 18, 18, 30, 0, 0, time.UTC),
+				time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC),
+				time.Date(2026, 10, 19, 18, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "weekly rule",
+			spec: "RRULE:FREQ=WEEKLY;BYDAY=MO,FR;BYHOUR=9",
+			want: []time.Time{
+				time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 23, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 26, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "last business day of the month",
+			spec: "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;BYHOUR=9",
+			want: []time.Time{
+				time.Date(2026, 10, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 30, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 12, 31, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "second tuesday of the month",
+			spec: "RRULE:FREQ=MONTHLY;BYDAY=2TU",
+			want: []time.Time{
+				time.Date(2026, 11, 10, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 12, 8, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "last day of the month",
+			spec: "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1",
+			want: []time.Time{
+				time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "monthly rule defaults to the first day",
+			spec: "RRULE:FREQ=MONTHLY",
+			want: []time.Time{time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "leap day",
+			spec: "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29",
+			want: []time.Time{time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "last friday of the year",
+			spec: "RRULE:FREQ=YEARLY;BYDAY=-1FR",
+			want: []time.Time{time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "rule with time zone",
+			spec: "CRON_TZ=Europe/Berlin RRULE:FREQ=DAILY;BYHOUR=9",
+			want: []time.Time{time.Date(2026, 10, 19, 9, 0, 0, 0, berlin)},
+		},
+		{
+			name: "rule until",
+			spec: "RRULE:FREQ=DAILY;BYHOUR=9;UNTIL=20261019",
+			want: []time.Time{time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), {}},
+		},
+		{
+			name: "rule without runs",
+			spec: "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+			want: []time.Time{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCronSpec(tt.spec)
+			if err != nil {
+				t.Fatalf("parseCronSpec(%q) error = %v", tt.spec, err)
+			}
+
+			next := tt.from
+			if next.IsZero() {
+				next = from
+			}
+			for _, want := range tt.want {
+				next = schedule.Next(next)
+				if !next.Equal(want) {
+					t.Fatalf("Next() = %v, want %v", next, want)
+				}
+				if next.IsZero() {
+					return
+				}
+			}
+		})
+	}
+}
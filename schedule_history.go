@@ -0,0 +1,389 @@
+package asyncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Default schedule history options.
+const (
+	defaultScheduleHistoryLimit = 100                 // Default number of runs kept per schedule
+	scheduleTaskIDPrefix        = "asyncer:schedule:" // Prefix of the IDs of the planned schedule tasks (see SchedulerServer)
+	scheduleRunLinkTTL          = 7 * 24 * time.Hour  // TTL of the links from the task IDs to the schedules, if the history has no max age
+	scheduleHistoryTasksTTL     = 30 * time.Second    // Interval of the queue servers refreshing the task names with the history
+)
+
+// scheduleHistoryTasksKey is the redis key of the names of the tasks scheduled with the history enabled.
+const scheduleHistoryTasksKey = "asyncer:schedule-history:tasks"
+
+// Schedule run statuses.
+const (
+	ScheduleRunEnqueueFailed = "enqueue_failed" // The task failed to enqueue
+	ScheduleRunPending       = "pending"        // The task is enqueued and not processed yet, or being processed
+	ScheduleRunSucceeded     = "succeeded"      // The task is processed successfully
+	ScheduleRunFailed        = "failed"         // The last attempt to process the task failed, it may be retried
+)
+
+type (
+	// ScheduleRun is a fire of a schedule with the processing outcome of its task.
+	ScheduleRun struct {
+		// ScheduleID is the identifier of the schedule (see ScheduleID).
+		ScheduleID string `json:"schedule_id"`
+		// TaskName is the name of the scheduled task.
+		TaskName string `json:"task_name"`
+		// TaskID is the ID of the enqueued task.
+		TaskID string `json:"task_id"`
+		// Queue is the queue of the enqueued task.
+		Queue string `json:"queue"`
+		// FiredAt is the time the schedule fired.
+		FiredAt time.Time `json:"fired_at"`
+		// CatchUp reports whether the run catches up a missed one (see MisfireRunOnce).
+		CatchUp bool `json:"catch_up,omitempty"`
+		// EnqueueError is the error of the enqueue, empty if the task was enqueued.
+		EnqueueError string `json:"enqueue_error,omitempty"`
+
+		// Status is the status of the run, one of the ScheduleRun* constants.
+		Status string `json:"status"`
+		// StartedAt is the start time of the last processing attempt, zero if it's not processed yet.
+		StartedAt time.Time `json:"started_at"`
+		// FinishedAt is the finish time of the last processing attempt, zero if it's not processed yet.
+		FinishedAt time.Time `json:"finished_at"`
+		// Duration is the processing duration of the last attempt.
+		Duration time.Duration `json:"duration,omitempty"`
+		// Retried is the number of times the task was retried before the last attempt.
+		Retried int `json:"retried,omitempty"`
+		// Error is the processing error of the last attempt, empty if it succeeded.
+		Error string `json:"error,omitempty"`
+	}
+
+	// scheduleRunOutcome is the processing outcome of the task of a schedule run.
+	scheduleRunOutcome struct {
+		StartedAt  time.Time `json:"started_at"`
+		FinishedAt time.Time `json:"finished_at"`
+		Retried    int       `json:"retried"`
+		Error      string    `json:"error,omitempty"`
+	}
+
+	// ScheduleHistory queries the runs of the schedules recorded by the schedulers
+	// with the history enabled (see SchedulerServer.EnableHistory).
+	ScheduleHistory struct {
+		redis redis.UniversalClient
+	}
+
+	// scheduleHistoryTasks caches the names of the tasks scheduled with the history enabled in the queue server,
+	// so the tasks of other handlers are not looked up in redis.
+	scheduleHistoryTasks struct {
+		mu       sync.Mutex
+		names    map[string]struct{}
+		loadedAt time.Time
+	}
+
+	// scheduleIDOption is the option to set the identifier of a schedule.
+	scheduleIDOption struct {
+		id string
+	}
+)
+
+// String returns the string representation of the option.
+func (o scheduleIDOption) String() string { return fmt.Sprintf("ScheduleID(%q)", o.id) }
+
+// Type returns the type of the option.
+func (o scheduleIDOption) Type() asynq.OptionType { return scheduleIDOpt }
+
+// Value returns the value of the option.
+func (o scheduleIDOption) Value() any { return o.id }
+
+// ScheduleID sets the identifier the schedule state (e.g. its history and last fire time) is kept under in redis.
+// By default, it's derived from the task name, the cron spec and the payload, so it changes with any of them.
+func ScheduleID(id string) TaskOption {
+	if id == "" {
+		return invalid(nil, "schedule id must not be empty")
+	}
+	return scheduleIDOption{id: id}
+}
+
+// EnableHistory enables the history of the schedule runs.
+// Every fire is recorded with the task ID and the enqueue error,
+// and the queue servers record the processing outcome of the task.
+// Up to the limit of the latest runs are kept per schedule, the runs older than the max age are removed.
+// Non-positive limit keeps the default 100 runs, zero max age keeps the runs regardless of their age.
+// The history can be queried with ScheduleHistory.
+// The runs of the schedules asynq can't parse (see SchedulerServer) are enqueued with the task IDs assigned by the scheduler,
+// so the TaskID option of those schedules is overridden.
+// It must be called before the schedules are registered and the server runs.
+func (srv *SchedulerServer) EnableHistory(limit int, maxAge time.Duration) {
+	if limit < 1 {
+		limit = defaultScheduleHistoryLimit
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.cnf.historyLimit = limit
+	srv.cnf.historyMaxAge = max(maxAge, 0)
+}
+
+// NewScheduleHistory creates a new schedule history query client.
+func NewScheduleHistory(redisClient redis.UniversalClient) *ScheduleHistory {
+	return &ScheduleHistory{redis: redisClient}
+}
+
+// Runs returns up to the limit of the latest runs of the schedule with the given ID, the latest first.
+// The schedule IDs of the registered schedules are returned by SchedulerServer.Schedules.
+func (h *ScheduleHistory) Runs(ctx context.Context, scheduleID string, limit int) ([]ScheduleRun, error) {
+	if limit < 1 {
+		return nil, nil
+	}
+
+	keys := scheduleHistoryKeys(scheduleID)
+	taskIDs, err := h.redis.ZRevRange(ctx, keys[0], 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, errors.Join(ErrFailedToGetScheduleHistory, err)
+	}
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+
+	fires, err := h.redis.HMGet(ctx, keys[1], taskIDs...).Result()
+	if err != nil {
+		return nil, errors.Join(ErrFailedToGetScheduleHistory, err)
+	}
+	outcomes, err := h.redis.HMGet(ctx, keys[2], taskIDs...).Result()
+	if err != nil {
+		return nil, errors.Join(ErrFailedToGetScheduleHistory, err)
+	}
+
+	runs := make([]ScheduleRun, 0, len(taskIDs))
+	for i := range taskIDs {
+		data, ok := fires[i].(string)
+		if !ok {
+			continue
+		}
+		var run ScheduleRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, errors.Join(ErrFailedToGetScheduleHistory, err)
+		}
+
+		run.Status = ScheduleRunPending
+		if run.EnqueueError != "" {
+			run.Status = ScheduleRunEnqueueFailed
+		}
+		if data, ok := outcomes[i].(string); ok {
+			var outcome scheduleRunOutcome
+			if err := json.Unmarshal([]byte(data), &outcome); err != nil {
+				return nil, errors.Join(ErrFailedToGetScheduleHistory, err)
+			}
+			run.StartedAt = outcome.StartedAt
+			run.FinishedAt = outcome.FinishedAt
+			run.Duration = outcome.FinishedAt.Sub(outcome.StartedAt)
+			run.Retried = outcome.Retried
+			run.Error = outcome.Error
+			run.Status = ScheduleRunSucceeded
+			if outcome.Error != "" {
+				run.Status = ScheduleRunFailed
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// recordScheduleRunScript stores the schedule run and removes the runs beyond the retention limits.
+var recordScheduleRunScript = redis.NewScript(`
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+redis.call("HSET", KEYS[2], ARGV[1], ARGV[3])
+
+local expired = redis.call("ZRANGE", KEYS[1], 0, -(tonumber(ARGV[4]) + 1))
+if tonumber(ARGV[5]) > 0 then
+	for _, id in ipairs(redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", "(" .. ARGV[5])) do
+		table.insert(expired, id)
+	end
+end
+for _, id in ipairs(expired) do
+	redis.call("ZREM", KEYS[1], id)
+	redis.call("HDEL", KEYS[2], id)
+	redis.call("HDEL", KEYS[3], id)
+end
+
+if tonumber(ARGV[6]) > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call("PEXPIRE", key, ARGV[6])
+	end
+end
+return 1
+`)
+
+// recordScheduleRunOutcomeScript stores the processing outcome of the schedule run, if the run is still kept.
+var recordScheduleRunOutcomeScript = redis.NewScript(`
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	redis.call("HSET", KEYS[3], ARGV[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// newScheduleRun returns a new run of the schedule, with the ID of the enqueued task.
+func newScheduleRun(rs *registeredSchedule, taskID, queue string, firedAt time.Time, catchUp bool) *ScheduleRun {
+	return &ScheduleRun{
+		ScheduleID: rs.id,
+		TaskName:   rs.taskName,
+		TaskID:     taskID,
+		Queue:      queue,
+		FiredAt:    firedAt,
+		CatchUp:    catchUp,
+	}
+}
+
+// plannedTaskID returns the ID of the task of the planned schedule run fired at the given time.
+func plannedTaskID(scheduleID string, t time.Time) string {
+	return fmt.Sprintf("%s%s:%d", scheduleTaskIDPrefix, scheduleID, t.Unix())
+}
+
+// trackScheduleHistory adds the task of the schedule to the tasks with the history,
+// so the queue servers record the processing outcomes of its runs.
+func (srv *SchedulerServer) trackScheduleHistory(rs *registeredSchedule) {
+	if err := srv.redis.SAdd(context.Background(), scheduleHistoryTasksKey, rs.taskName).Err(); err != nil {
+		srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: failed to track history of scheduled task %q: %v", rs.taskName, err))
+	}
+}
+
+// recordRun stores the schedule run in the history.
+// The ID of a task not enqueued by the planner is linked to the schedule,
+// so the queue servers can find the run to record the processing outcome.
+func (srv *SchedulerServer) recordRun(run *ScheduleRun) {
+	ctx := context.Background()
+	member := run.TaskID
+	switch {
+	case member == "":
+		member = fmt.Sprintf("enqueue-failed:%d", time.Now().UnixNano())
+	case !strings.HasPrefix(member, scheduleTaskIDPrefix):
+		ttl := srv.cnf.historyMaxAge
+		if ttl <= 0 {
+			ttl = scheduleRunLinkTTL
+		}
+		if err := srv.redis.Set(ctx, scheduleRunLinkKey(member), run.ScheduleID, ttl).Err(); err != nil {
+			srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: failed to record run of scheduled task %q: %v", run.TaskName, err))
+		}
+	}
+
+	data, err := json.Marshal(run)
+	if err == nil {
+		var minScore int64
+		if srv.cnf.historyMaxAge > 0 {
+			minScore = time.Now().Add(-srv.cnf.historyMaxAge).UnixMilli()
+		}
+		err = recordScheduleRunScript.Run(ctx, srv.redis, scheduleHistoryKeys(run.ScheduleID),
+			member, run.FiredAt.UnixMilli(), data, srv.cnf.historyLimit, minScore, srv.cnf.historyMaxAge.Milliseconds(),
+		).Err()
+	}
+	if err != nil {
+		srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: failed to record run of scheduled task %q: %v", run.TaskName, err))
+	}
+}
+
+// scheduleHistory records the processing outcome of the tasks enqueued by the schedulers with the history enabled.
+// Other tasks are passed through as is.
+func (srv *QueueServer) scheduleHistory(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		taskID, _ := asynq.GetTaskID(ctx)
+		scheduleID, ok := srv.scheduleIDOfTask(ctx, t.Type(), taskID)
+		if !ok {
+			return next.ProcessTask(ctx, t)
+		}
+
+		outcome := scheduleRunOutcome{StartedAt: time.Now()}
+		outcome.Retried, _ = asynq.GetRetryCount(ctx)
+
+		err := next.ProcessTask(ctx, t)
+		if IsRequeueError(err) {
+			// The task is requeued before processing (e.g. by MaxConcurrency), it's not an outcome.
+			return err
+		}
+
+		outcome.FinishedAt = time.Now()
+		if err != nil {
+			outcome.Error = err.Error()
+		}
+		if data, merr := json.Marshal(outcome); merr == nil {
+			// The error is ignored on purpose: the history must not affect the task processing.
+			_ = recordScheduleRunOutcomeScript.Run(context.WithoutCancel(ctx), srv.redis, scheduleHistoryKeys(scheduleID), taskID, data).Err()
+		}
+
+		return err
+	})
+}
+
+// scheduleIDOfTask returns the schedule ID of the task enqueued by a scheduler with the history enabled.
+// The schedule ID of a planned run is encoded in the task ID, other runs are looked up by their links.
+func (srv *QueueServer) scheduleIDOfTask(ctx context.Context, taskName, taskID string) (string, bool) {
+	if scheduleID, ok := scheduleIDFromTaskID(taskID); ok {
+		return scheduleID, true
+	}
+	if taskID == "" || !srv.history.has(ctx, srv.redis, taskName) {
+		return "", false
+	}
+	scheduleID, err := srv.redis.Get(ctx, scheduleRunLinkKey(taskID)).Result()
+	if err != nil {
+		return "", false
+	}
+	return scheduleID, true
+}
+
+// has reports whether the task is scheduled with the history enabled.
+// The task names are reloaded from redis once the cache is stale, on failure the stale names are used.
+func (c *scheduleHistoryTasks) has(ctx context.Context, rdb redis.UniversalClient, taskName string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.loadedAt) >= scheduleHistoryTasksTTL {
+		if names, err := rdb.SMembers(ctx, scheduleHistoryTasksKey).Result(); err == nil {
+			c.names = make(map[string]struct{}, len(names))
+			for _, name := range names {
+				c.names[name] = struct{}{}
+			}
+		}
+		c.loadedAt = time.Now()
+	}
+
+	_, ok := c.names[taskName]
+	return ok
+}
+
+// scheduleIDFromTaskID returns the schedule ID of the planned schedule run task.
+func scheduleIDFromTaskID(taskID string) (string, bool) {
+	rest, ok := strings.CutPrefix(taskID, scheduleTaskIDPrefix)
+	if !ok {
+		return "", false
+	}
+	i := strings.LastIndexByte(rest, ':')
+	if i <= 0 {
+		return "", false
+	}
+	if _, err := strconv.ParseInt(rest[i+1:], 10, 64); err != nil {
+		return "", false
+	}
+	return rest[:i], true
+}
+
+// scheduleRunLinkKey returns the redis key of the link from the task ID to the schedule of the run.
+func scheduleRunLinkKey(taskID string) string {
+	return fmt.Sprintf("asyncer:schedule-runs:%s", taskID)
+}
+
+// scheduleHistoryKeys returns the redis keys of the schedule history:
+// the runs ordered by the fire time, the runs data, and the processing outcomes of the runs.
+// The keys share the hash tag of the schedule ID, so the scripts can use them together on a redis cluster.
+func scheduleHistoryKeys(scheduleID string) []string {
+	key := fmt.Sprintf("asyncer:schedule-history:{%s}", scheduleID)
+	return []string{key, key + ":fires", key + ":outcomes"}
+}
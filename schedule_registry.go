@@ -0,0 +1,353 @@
+package asyncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultScheduleSyncInterval is the interval of the full schedules sync,
+// in case an update notification was missed, e.g. during a redis reconnect.
+const defaultScheduleSyncInterval = time.Minute
+
+type (
+	// Schedule is a scheduled task stored in the schedule registry.
+	Schedule struct {
+		// ID is the unique identifier of the schedule, e.g. "report:customer-42".
+		ID string `json:"id"`
+		// CronSpec is the cron spec of the schedule.
+		CronSpec string `json:"cron_spec"`
+		// TaskName is the name of the scheduled task.
+		TaskName string `json:"task_name"`
+		// Payload is the JSON payload of the scheduled task, it's optional.
+		Payload json.RawMessage `json:"payload,omitempty"`
+		// Queue is the queue name of the scheduled task, it's optional.
+		Queue string `json:"queue,omitempty"`
+		// MaxRetry is the maximum number of retries of the scheduled task, it's optional.
+		MaxRetry *int `json:"max_retry,omitempty"`
+		// Timeout is the processing timeout of the scheduled task, it's optional.
+		Timeout Duration `json:"timeout,omitempty"`
+		// Unique is the uniqueness TTL of the scheduled task, it's optional.
+		Unique Duration `json:"unique,omitempty"`
+		// TimeZone is the time zone of the cron spec, e.g. "Europe/Berlin", it's optional.
+		// The scheduler location is used by default.
+		TimeZone string `json:"time_zone,omitempty"`
+	}
+
+	// ScheduleRegistry is a set of schedules stored in redis.
+	// Schedules can be added, updated and removed at runtime,
+	// and all scheduler servers watching the registry apply the changes (see SchedulerServer.WatchSchedules).
+	ScheduleRegistry struct {
+		redis redis.UniversalClient
+		name  string
+	}
+
+	// scheduleEntry is a schedule registered in the scheduler server.
+	scheduleEntry struct {
+		raw     string // raw schedule JSON, used to detect changes
+		entryID string // scheduler entry ID, empty if the schedule failed to register
+	}
+)
+
+// NewScheduleRegistry creates a new schedule registry stored in redis under the given name.
+func NewScheduleRegistry(redisClient redis.UniversalClient, name string) *ScheduleRegistry {
+	return &ScheduleRegistry{redis: redisClient, name: name}
+}
+
+// The scripts take the updates channel as the first argument rather than a key,
+// so the registry hash is the only key and the scripts run on a redis cluster.
+
+// addScheduleScript adds the schedule if it doesn't exist and notifies the watchers.
+var addScheduleScript = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], ARGV[2], ARGV[3]) == 0 then
+	return 0
+end
+redis.call("PUBLISH", ARGV[1], ARGV[2])
+return 1
+`)
+
+// updateScheduleScript updates the schedule if it exists and notifies the watchers.
+var updateScheduleScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[2], ARGV[3])
+redis.call("PUBLISH", ARGV[1], ARGV[2])
+return 1
+`)
+
+// removeScheduleScript removes the schedule if it exists and notifies the watchers.
+var removeScheduleScript = redis.NewScript(`
+if redis.call("HDEL", KEYS[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("PUBLISH", ARGV[1], ARGV[2])
+return 1
+`)
+
+// Add adds the schedule to the registry.
+// It returns an error wrapping ErrScheduleAlreadyExists if there is a schedule with the same ID.
+func (r *ScheduleRegistry) Add(ctx context.Context, s Schedule) error {
+	if err := r.write(ctx, addScheduleScript, s); err != nil {
+		if errors.Is(err, errScheduleNotChanged) {
+			err = ErrScheduleAlreadyExists
+		}
+		return errors.Join(ErrFailedToAddSchedule, err)
+	}
+	return nil
+}
+
+// Update replaces the schedule with the same ID in the registry.
+// It returns an error wrapping ErrScheduleNotFound if there is no such schedule.
+func (r *ScheduleRegistry) Update(ctx context.Context, s Schedule) error {
+	if err := r.write(ctx, updateScheduleScript, s); err != nil {
+		if errors.Is(err, errScheduleNotChanged) {
+			err = ErrScheduleNotFound
+		}
+		return errors.Join(ErrFailedToUpdateSchedule, err)
+	}
+	return nil
+}
+
+// Remove removes the schedule with the given ID from the registry.
+// It returns an error wrapping ErrScheduleNotFound if there is no such schedule.
+func (r *ScheduleRegistry) Remove(ctx context.Context, id string) error {
+	removed, err := removeScheduleScript.Run(ctx, r.redis, []string{r.key()}, r.channel(), id).Int()
+	if err != nil {
+		return errors.Join(ErrFailedToRemoveSchedule, err)
+	}
+	if removed == 0 {
+		return errors.Join(ErrFailedToRemoveSchedule, ErrScheduleNotFound)
+	}
+	return nil
+}
+
+// Get returns the schedule with the given ID.
+// It returns ErrScheduleNotFound if there is no such schedule.
+func (r *ScheduleRegistry) Get(ctx context.Context, id string) (Schedule, error) {
+	raw, err := r.redis.HGet(ctx, r.key(), id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Schedule{}, ErrScheduleNotFound
+		}
+		return Schedule{}, err
+	}
+
+	var s Schedule
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return Schedule{}, err
+	}
+	return s, nil
+}
+
+// List returns all schedules of the registry ordered by ID.
+func (r *ScheduleRegistry) List(ctx context.Context) ([]Schedule, error) {
+	raw, err := r.redis.HGetAll(ctx, r.key()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]Schedule, 0, len(raw))
+	for id, data := range raw {
+		var s Schedule
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", id, err)
+		}
+		schedules = append(schedules, s)
+	}
+	slices.SortFunc(schedules, func(a, b Schedule) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return schedules, nil
+}
+
+// errScheduleNotChanged is returned by write if the script didn't change the registry.
+var errScheduleNotChanged = errors.New("schedule not changed")
+
+// write validates the schedule and stores it with the given script.
+func (r *ScheduleRegistry) write(ctx context.Context, script *redis.Script, s Schedule) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	changed, err := script.Run(ctx, r.redis, []string{r.key()}, r.channel(), s.ID, data).Int()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return errScheduleNotChanged
+	}
+	return nil
+}
+
+// key returns the redis key of the registry.
+func (r *ScheduleRegistry) key() string {
+	return fmt.Sprintf("asyncer:schedules:%s", r.name)
+}
+
+// channel returns the redis channel of the registry updates.
+func (r *ScheduleRegistry) channel() string {
+	return fmt.Sprintf("asyncer:schedules:%s:updates", r.name)
+}
+
+// validate returns an error if the schedule misses any required field, its cron spec is invalid or its time zone is unknown.
+func (s Schedule) validate() error {
+	switch {
+	case s.ID == "":
+		return ErrScheduleIDIsEmpty
+	case s.TaskName == "":
+		return ErrTaskNameIsEmpty
+	case len(s.Payload) > 0 && !json.Valid(s.Payload):
+		return fmt.Errorf("schedule %q payload is not a valid JSON", s.ID)
+	}
+	if err := ValidateCronSpec(s.CronSpec); err != nil {
+		return err
+	}
+	if s.TimeZone != "" {
+		if _, err := loadLocation(s.TimeZone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// options returns the task options of the schedule.
+func (s Schedule) options() []TaskOption {
+	var opts []TaskOption
+	if s.Queue != "" {
+		opts = append(opts, asynq.Queue(s.Queue))
+	}
+	if s.MaxRetry != nil {
+		opts = append(opts, MaxRetry(*s.MaxRetry))
+	}
+	if s.Timeout > 0 {
+		opts = append(opts, Timeout(time.Duration(s.Timeout)))
+	}
+	if s.Unique > 0 {
+		opts = append(opts, Unique(time.Duration(s.Unique)))
+	}
+	if s.TimeZone != "" {
+		opts = append(opts, TimeZone(s.TimeZone))
+	}
+	return opts
+}
+
+// taskScheduler returns the task scheduler of the schedule.
+func (s Schedule) taskScheduler() TaskScheduler {
+	if len(s.Payload) == 0 {
+		return NewTaskScheduler(s.CronSpec, s.TaskName, s.options()...)
+	}
+	return NewTaskSchedulerWithPayload(s.CronSpec, s.TaskName, s.Payload, s.options()...)
+}
+
+// WatchSchedules registers the schedules of the registry in the scheduler,
+// and keeps them in sync with the registry until the context is canceled.
+// Schedules which fail to register (e.g. because of an invalid cron spec) are skipped and logged.
+// It returns a function that can be used to run the watcher in an error group.
+// E.g.:
+//
+//	registry := asyncer.NewScheduleRegistry(redisClient, "reports")
+//
+//	eg, ctx := errgroup.WithContext(context.Background())
+//	eg.Go(schedulerServer.Run())
+//	eg.Go(schedulerServer.WatchSchedules(ctx, registry))
+//
+//	// Somewhere else, e.g. in an admin API:
+//	registry.Add(ctx, asyncer.Schedule{
+//		ID:       "report:customer-42",
+//		CronSpec: "0 8 * * *",
+//		TaskName: "report",
+//		Payload:  json.RawMessage(`{"customer_id":42}`),
+//	})
+func (srv *SchedulerServer) WatchSchedules(ctx context.Context, registry *ScheduleRegistry) func() error {
+	return func() error {
+		// Subscribe before loading the current schedules, so no update is missed.
+		pubsub := srv.redis.Subscribe(ctx, registry.channel())
+		defer pubsub.Close()
+
+		entries := make(map[string]scheduleEntry)
+		if err := srv.syncSchedules(ctx, registry, entries); err != nil {
+			return err
+		}
+
+		ticker := time.NewTicker(defaultScheduleSyncInterval)
+		defer ticker.Stop()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-srv.done:
+				return nil
+			case _, ok := <-ch:
+				if !ok {
+					return nil
+				}
+			case <-ticker.C:
+			}
+
+			if err := srv.syncSchedules(ctx, registry, entries); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// syncSchedules registers the new and changed schedules of the registry
+// and unregisters the removed ones.
+// The entries map keeps the registered schedules by ID between the calls.
+func (srv *SchedulerServer) syncSchedules(ctx context.Context, registry *ScheduleRegistry, entries map[string]scheduleEntry) error {
+	raw, err := srv.redis.HGetAll(ctx, registry.key()).Result()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return errors.Join(ErrFailedToWatchSchedules, err)
+	}
+
+	// Unregister removed and changed schedules.
+	for id, entry := range entries {
+		if data, ok := raw[id]; ok && data == entry.raw {
+			continue
+		}
+		if entry.entryID != "" {
+			if err := srv.unregister(entry.entryID); err != nil {
+				srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to unregister schedule %q: %v", id, err))
+			}
+		}
+		delete(entries, id)
+	}
+
+	// Register new and changed schedules.
+	for id, data := range raw {
+		if _, ok := entries[id]; ok {
+			continue
+		}
+
+		// Failed schedules are remembered as well, so they are not retried until changed.
+		entry := scheduleEntry{raw: data}
+		var s Schedule
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to decode schedule %q: %v", id, err))
+		} else if entry.entryID, err = srv.schedule(s.taskScheduler()); err != nil {
+			srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to register schedule %q: %v", id, err))
+		}
+		entries[id] = entry
+	}
+
+	return nil
+}
@@ -1,72 +1,608 @@
 package asyncer
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
 	"golang.org/x/sync/errgroup"
 )
 
+// schedulePlanInterval is the interval between the plannings of the schedules asynq can't parse
+// (see SchedulerServer). Their runs are enqueued up to two intervals ahead.
+const schedulePlanInterval = 30 * time.Second
+
 type (
 	// SchedulerServer is a wrapper for asynq.Scheduler.
+	// The schedules asynq can't parse (one-off @at times, recurrence rules, schedules with calendar exclusions
+	// or with the Jitter option) are not registered in asynq.Scheduler: their runs are enqueued ahead of the fire times as scheduled tasks.
+	//
+	// Schedules accept the schedule options (TimeZone, ScheduleID, the misfire policies, Jitter, Spread,
+	// SkipExcluded and ShiftExcluded) along with the task options. They configure the schedule only
+	// and have no effect when passed to the enqueuer.
 	SchedulerServer struct {
-		asynq *asynq.Scheduler
+		mu        sync.Mutex
+		asynq     *asynq.Scheduler // nil while the server is not running or the instance is not the leader
+		client    *asynq.Client
+		cnf       schedulerConfig
+		redis     redis.UniversalClient
+		schedules map[string]*registeredSchedule // by scheduler entry ID
+		firing    []*registeredSchedule          // schedules whose tasks are being enqueued by asynq.Scheduler
+		entries   int                            // number of registrations, used to generate the entry IDs
+		planning  chan struct{}                  // triggers the planning of the schedules asynq can't parse
+		leader    *leaderElection                // nil if the leader election is disabled
+		strict    bool                           // report invalid options instead of coercing them
+		optsErr   error                          // invalid scheduler options, reported in strict mode
+		wg        sync.WaitGroup
+		done      chan struct{}
+		once      sync.Once
+	}
+
+	// registeredSchedule is a schedule registered in the scheduler server.
+	registeredSchedule struct {
+		id        string // stable schedule identifier, used to keep the schedule state in redis
+		taskName  string
+		cronSpec  string
+		spec      string // cron spec registered in asynq.Scheduler, empty if the runs are planned by the server
+		location  *time.Location
+		schedule  cron.Schedule
+		task      *asynq.Task
+		opts      []asynq.Option
+		entryOpts []asynq.Option // options of the asynq.Scheduler entry, with the delay of the Spread option
+		entryID   string         // asynq.Scheduler entry ID, empty if the schedule is not registered in it
+		misfire   int            // number of missed runs to catch up, zero to skip them
 	}
 
 	// SchedulerServerOption is a function that configures a SchedulerServer.
 	SchedulerServerOption func(*asynq.SchedulerOpts)
+
+	// schedulerConfig is the scheduler server config.
+	// Besides the asynq scheduler options, it keeps the settings of the asyncer features,
+	// which are set by the server methods (e.g. EnableHistory).
+	schedulerConfig struct {
+		asynq.SchedulerOpts
+		historyLimit  int           // number of runs kept per schedule, zero if the history is disabled
+		historyMaxAge time.Duration // max age of the kept runs, zero for no limit
+	}
 )
 
 // NewSchedulerServer creates a new scheduler client and returns the server.
 func NewSchedulerServer(redisClient redis.UniversalClient, opts ...SchedulerServerOption) *SchedulerServer {
 	// setup asynq scheduler config
-	cnf := &asynq.SchedulerOpts{
-		LogLevel: asynq.ErrorLevel,
-		Location: time.UTC,
+	cnf := &schedulerConfig{
+		SchedulerOpts: asynq.SchedulerOpts{
+			LogLevel: asynq.ErrorLevel,
+			Location: time.UTC,
+		},
 	}
 
 	// Apply options
 	for _, opt := range opts {
-		opt(cnf)
+		opt(&cnf.SchedulerOpts)
+	}
+	optsErr := normalizeSchedulerOpts(&cnf.SchedulerOpts)
+
+	// The same logger is used by asynq.Scheduler and the server itself.
+	if cnf.Logger == nil {
+		cnf.Logger = NewSlogAdapter(slog.Default())
 	}
 
 	return &SchedulerServer{
-		asynq: asynq.NewSchedulerFromRedisClient(redisClient, cnf),
+		client:    asynq.NewClientFromRedisClient(redisClient),
+		cnf:       *cnf,
+		redis:     redisClient,
+		schedules: make(map[string]*registeredSchedule),
+		planning:  make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		optsErr:   optsErr,
 	}
 }
 
+// SetStrictOptions enables or disables the strict mode of the server.
+// By default, invalid scheduler and task options are coerced to the closest valid values (see ValidateTaskOptions),
+// e.g. an unknown scheduler location falls back to UTC.
+// In strict mode, the server fails to run with an error describing all invalid scheduler options instead,
+// and schedules with invalid task options are rejected. It must be called before the schedules are registered.
+func (srv *SchedulerServer) SetStrictOptions(strict bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.strict = strict
+}
+
 // ScheduleTask schedules a task based on the given cron specification and task name.
+// The task is enqueued without a payload, use ScheduleTaskWithPayload to pass one to the handler.
 // It returns an error if the cron specification or task name is empty, or if there was an error registering the task.
+// Invalid cron specs are rejected with an error wrapping ErrInvalidCronSpec.
+// The cron spec is evaluated in the time zone set by the TimeZone option, or in the scheduler location by default.
+// Invalid task options are coerced to the closest valid values (see ValidateTaskOptions), unless in strict mode.
 func (srv *SchedulerServer) ScheduleTask(cronSpec, taskName string, opts ...TaskOption) error {
+	return srv.ScheduleTaskWithPayload(cronSpec, taskName, nil, opts...)
+}
+
+// ScheduleTaskWithPayload schedules a task with the given payload based on the cron specification and task name.
+// The payload is encoded as JSON, so the task can be handled by a handler created with HandlerFunc.
+// Nil payload schedules the task without a payload.
+// It returns an error if the cron specification or task name is empty, if the payload can't be encoded,
+// or if there was an error registering the task.
+// In strict mode (see SetStrictOptions), it also returns an error wrapping ErrInvalidTaskOption
+// if any task option is invalid.
+func (srv *SchedulerServer) ScheduleTaskWithPayload(cronSpec, taskName string, payload any, opts ...TaskOption) error {
+	_, err := srv.register(cronSpec, taskName, payload, opts)
+	return err
+}
+
+// register registers the schedule of the task and returns the scheduler entry ID.
+// The missed runs of the schedule are caught up if the server is running.
+func (srv *SchedulerServer) register(cronSpec, taskName string, payload any, opts []TaskOption) (string, error) {
 	if cronSpec == "" {
-		return errors.Join(ErrFailedToScheduleTask, ErrCronSpecIsEmpty)
+		return "", errors.Join(ErrFailedToScheduleTask, ErrCronSpecIsEmpty)
 	}
 	if taskName == "" {
-		return errors.Join(ErrFailedToScheduleTask, ErrTaskNameIsEmpty)
+		return "", errors.Join(ErrFailedToScheduleTask, ErrTaskNameIsEmpty)
+	}
+
+	srv.mu.Lock()
+	strict := srv.strict
+	srv.mu.Unlock()
+
+	opts, err := normalizeOptions(opts, strict)
+	if err != nil {
+		return "", errors.Join(ErrFailedToScheduleTask, err)
+	}
+
+	spec, err := cronSpecWithTimeZone(cronSpec, opts)
+	if err != nil {
+		return "", errors.Join(ErrFailedToScheduleTask, err)
+	}
+	schedule, err := parseCronSpec(spec)
+	if err != nil {
+		return "", errors.Join(ErrFailedToScheduleTask, err)
+	}
+	loc, err := srv.scheduleLocation(spec)
+	if err != nil {
+		return "", errors.Join(ErrFailedToScheduleTask, err)
+	}
+	if at, ok := schedule.(*atSchedule); ok && at.location != nil {
+		// The time zone of the @at time, e.g. for the calendar exclusions.
+		loc = at.location
+	}
+	schedule = withCalendars(schedule, loc, opts)
+
+	var data []byte
+	if payload != nil {
+		if data, err = json.Marshal(payload); err != nil {
+			return "", errors.Join(ErrFailedToScheduleTask, ErrFailedToMarshalPayload, err)
+		}
+	}
+
+	id := scheduleID(taskName, spec, data)
+	if opt, ok := findOption[scheduleIDOption](opts); ok {
+		id = opt.id
+	}
+
+	rs := &registeredSchedule{
+		id:       id,
+		taskName: taskName,
+		cronSpec: cronSpec,
+		location: loc,
+		schedule: schedule,
+		task:     asynq.NewTask(taskName, data),
+		opts:     opts,
+	}
+	if opt, ok := findOption[misfireOption](opts); ok {
+		rs.misfire = opt.runs
+	}
+	// asynq.Scheduler parses the cron specs the same way, except for the extended syntax.
+	// It enqueues every fire with the same options, so the jittered schedules are planned by the server,
+	// which draws a new delay for every fire.
+	_, extended, _ := parseExtendedSpec(spec)
+	_, calendars := findOption[calendarOption](opts)
+	_, jitter := findOption[jitterOption](opts)
+	if !extended && !calendars && !jitter {
+		rs.spec = spec
+		rs.entryOpts = opts
+		if delay := fireDelay(rs); delay > 0 {
+			rs.entryOpts = append(slices.Clone(opts), asynq.ProcessIn(delay))
+		}
+	}
+	if srv.cnf.historyLimit > 0 {
+		srv.trackScheduleHistory(rs)
+	}
+
+	srv.mu.Lock()
+	srv.entries++
+	entryID := strconv.Itoa(srv.entries)
+	if srv.asynq != nil {
+		if err := srv.registerEntry(srv.asynq, rs); err != nil {
+			srv.mu.Unlock()
+			return "", errors.Join(ErrFailedToScheduleTask, err)
+		}
+	}
+	srv.schedules[entryID] = rs
+	srv.mu.Unlock()
+
+	if srv.active() {
+		// Catch up the runs missed while the schedule was not registered.
+		srv.catchUp(rs)
+		if rs.spec == "" {
+			srv.replan()
+		}
+	}
+
+	return entryID, nil
+}
+
+// registerEntry registers the schedule in the asynq scheduler, unless its runs are planned by the server.
+func (srv *SchedulerServer) registerEntry(scheduler *asynq.Scheduler, rs *registeredSchedule) error {
+	if rs.spec == "" {
+		return nil
+	}
+	entryID, err := scheduler.Register(rs.spec, rs.task, rs.entryOpts...)
+	if err != nil {
+		return err
+	}
+	rs.entryID = entryID
+	return nil
+}
+
+// unregister removes the schedule with the given scheduler entry ID.
+func (srv *SchedulerServer) unregister(entryID string) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	rs, ok := srv.schedules[entryID]
+	if !ok {
+		return fmt.Errorf("no scheduler entry %q", entryID)
+	}
+	delete(srv.schedules, entryID)
+
+	if srv.asynq != nil && rs.entryID != "" {
+		return srv.asynq.Unregister(rs.entryID)
+	}
+	return nil
+}
+
+// registeredSchedules returns the schedules registered in the scheduler server.
+func (srv *SchedulerServer) registeredSchedules() []*registeredSchedule {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	res := make([]*registeredSchedule, 0, len(srv.schedules))
+	for _, rs := range srv.schedules {
+		res = append(res, rs)
+	}
+	return res
+}
+
+// start starts the asynq scheduler with the registered schedules and catches up their missed runs.
+// It's called when the server runs, or when the instance becomes the leader if the leader election is enabled.
+func (srv *SchedulerServer) start() error {
+	srv.mu.Lock()
+	if srv.asynq != nil {
+		srv.mu.Unlock()
+		return nil
+	}
+
+	opts := srv.cnf.SchedulerOpts
+	opts.PreEnqueueFunc = srv.preEnqueue
+	opts.PostEnqueueFunc = srv.postEnqueue
+	opts.EnqueueErrorHandler = srv.enqueueError
+
+	scheduler := asynq.NewSchedulerFromRedisClient(srv.redis, &opts)
+	for _, rs := range srv.schedules {
+		if err := srv.registerEntry(scheduler, rs); err != nil {
+			srv.mu.Unlock()
+			return fmt.Errorf("task %q: %w", rs.taskName, err)
+		}
+	}
+	if err := scheduler.Start(); err != nil {
+		srv.mu.Unlock()
+		return err
+	}
+	srv.asynq = scheduler
+	srv.mu.Unlock()
+
+	// Catch up the runs missed while no scheduler was running.
+	for _, rs := range srv.registeredSchedules() {
+		srv.catchUp(rs)
+	}
+	srv.replan()
+
+	return nil
+}
+
+// stop shuts down the asynq scheduler, e.g. when the instance loses the leadership.
+func (srv *SchedulerServer) stop() {
+	srv.mu.Lock()
+	scheduler := srv.asynq
+	srv.asynq = nil
+	srv.mu.Unlock()
+
+	if scheduler != nil {
+		scheduler.Shutdown()
+	}
+}
+
+// active reports whether the instance enqueues the scheduled tasks:
+// the server is running and the instance is the leader if the leader election is enabled.
+func (srv *SchedulerServer) active() bool {
+	srv.mu.Lock()
+	running := srv.asynq != nil
+	srv.mu.Unlock()
+
+	return running && srv.isLeader()
+}
+
+// preEnqueue remembers the schedule whose task is being enqueued by the asynq scheduler,
+// so the enqueued task can be attributed to it in postEnqueue, and calls the pre enqueue function of the options.
+func (srv *SchedulerServer) preEnqueue(task *asynq.Task, opts []asynq.Option) {
+	srv.mu.Lock()
+	for _, rs := range srv.schedules {
+		if rs.task == task {
+			srv.firing = append(srv.firing, rs)
+			break
+		}
 	}
+	srv.mu.Unlock()
 
-	if _, err := srv.asynq.Register(cronSpec, asynq.NewTask(taskName, nil, opts...)); err != nil {
-		return errors.Join(ErrFailedToScheduleTask, err)
+	if srv.cnf.PreEnqueueFunc != nil {
+		srv.cnf.PreEnqueueFunc(task, opts)
+	}
+}
+
+// postEnqueue records the run of the schedule whose task was enqueued by the asynq scheduler:
+// the last fire time for the misfire policy, and the run for the schedule history.
+// The failed enqueues are recorded by enqueueError, which gets the task of the schedule.
+func (srv *SchedulerServer) postEnqueue(info *asynq.TaskInfo, err error) {
+	if err == nil {
+		rs := srv.fired(func(rs *registeredSchedule) bool {
+			return rs.task.Type() == info.Type && bytes.Equal(rs.task.Payload(), info.Payload) &&
+				queueFromOptions(defaultQueueName, rs.entryOpts) == info.Queue
+		})
+		if rs != nil {
+			now := time.Now()
+			srv.recordFire(rs, now)
+			if srv.cnf.historyLimit > 0 {
+				srv.recordRun(newScheduleRun(rs, info.ID, info.Queue, now, false))
+			}
+		}
 	}
 
+	if srv.cnf.PostEnqueueFunc != nil {
+		srv.cnf.PostEnqueueFunc(info, err)
+	}
+}
+
+// enqueueError records the failed enqueue of the schedule task by the asynq scheduler,
+// and calls the enqueue error handler of the options.
+func (srv *SchedulerServer) enqueueError(task *asynq.Task, opts []asynq.Option, err error) {
+	srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to enqueue scheduled task %q: %v", task.Type(), err))
+
+	rs := srv.fired(func(rs *registeredSchedule) bool { return rs.task == task })
+	if rs != nil && srv.cnf.historyLimit > 0 {
+		run := newScheduleRun(rs, "", queueFromOptions(defaultQueueName, opts), time.Now(), false)
+		run.EnqueueError = err.Error()
+		srv.recordRun(run)
+	}
+
+	if srv.cnf.EnqueueErrorHandler != nil {
+		srv.cnf.EnqueueErrorHandler(task, opts, err)
+	}
+}
+
+// fired removes the first schedule being enqueued by the asynq scheduler which matches, and returns it.
+// Schedules with identical tasks fired at the same time can't be told apart, either of them is returned.
+func (srv *SchedulerServer) fired(match func(rs *registeredSchedule) bool) *registeredSchedule {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for i, rs := range srv.firing {
+		if match(rs) {
+			srv.firing = slices.Delete(srv.firing, i, i+1)
+			return rs
+		}
+	}
 	return nil
 }
 
+// enqueue enqueues the task of the schedule for a run not fired by the asynq scheduler:
+// a run of a schedule asynq can't parse, or a catch-up of a missed run.
+// It calls the enqueue hooks of the options and records the run in the schedule history if it's enabled.
+// It reports false if the task is not enqueued.
+func (srv *SchedulerServer) enqueue(rs *registeredSchedule, opts []asynq.Option, firedAt time.Time, catchUp bool) bool {
+	if srv.cnf.PreEnqueueFunc != nil {
+		srv.cnf.PreEnqueueFunc(rs.task, opts)
+	}
+	info, err := srv.client.Enqueue(rs.task, opts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		// The run is already enqueued by another scheduler instance.
+		return false
+	}
+	if srv.cnf.PostEnqueueFunc != nil {
+		srv.cnf.PostEnqueueFunc(info, err)
+	}
+
+	var run *ScheduleRun
+	if srv.cnf.historyLimit > 0 {
+		run = newScheduleRun(rs, "", queueFromOptions(defaultQueueName, opts), firedAt, catchUp)
+	}
+	if err != nil {
+		srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to enqueue scheduled task %q: %v", rs.taskName, err))
+		if run != nil {
+			run.EnqueueError = err.Error()
+			srv.recordRun(run)
+		}
+		if srv.cnf.EnqueueErrorHandler != nil {
+			srv.cnf.EnqueueErrorHandler(rs.task, opts, err)
+		}
+		return false
+	}
+
+	srv.log(asynq.DebugLevel, fmt.Sprintf("asyncer: enqueued scheduled task %q: id=%s queue=%s", rs.taskName, info.ID, info.Queue))
+	if run != nil {
+		run.TaskID = info.ID
+		srv.recordRun(run)
+	}
+	return true
+}
+
+// replan triggers the planning of the schedules asynq can't parse, e.g. after a new one is registered.
+func (srv *SchedulerServer) replan() {
+	select {
+	case srv.planning <- struct{}{}:
+	default:
+	}
+}
+
+// runPlanner enqueues the runs of the schedules asynq can't parse ahead of their fire times,
+// until the scheduler is shut down. The runs are enqueued only while the instance is active.
+func (srv *SchedulerServer) runPlanner() {
+	defer srv.wg.Done()
+
+	ticker := time.NewTicker(schedulePlanInterval)
+	defer ticker.Stop()
+
+	planned := make(map[*registeredSchedule]time.Time)
+	for {
+		if srv.active() {
+			srv.plan(planned, time.Now().Add(2*schedulePlanInterval))
+		} else {
+			clear(planned)
+		}
+
+		select {
+		case <-srv.done:
+			return
+		case <-ticker.C:
+		case <-srv.planning:
+		}
+	}
+}
+
+// plan enqueues the runs of the schedules asynq can't parse up to the given time,
+// each run as a task processed at its fire time.
+// The task ID is derived from the schedule ID and the fire time, so the run is enqueued once
+// even if several instances plan it. The TaskID option of the schedule is overridden.
+// The planned map keeps the last planned fire time of the schedules between the calls.
+func (srv *SchedulerServer) plan(planned map[*registeredSchedule]time.Time, until time.Time) {
+	schedules := srv.registeredSchedules()
+	for rs := range planned {
+		if !slices.Contains(schedules, rs) {
+			delete(planned, rs)
+		}
+	}
+
+	now := time.Now()
+	for _, rs := range schedules {
+		if rs.spec != "" {
+			continue
+		}
+		from, ok := planned[rs]
+		if !ok {
+			from = now
+		}
+		for t := rs.schedule.Next(from.In(rs.location)); !t.IsZero() && !t.After(until); t = rs.schedule.Next(t) {
+			opts := append(slices.Clone(rs.opts), asynq.ProcessAt(t.Add(fireDelay(rs))), asynq.TaskID(plannedTaskID(rs.id, t)))
+			if srv.enqueue(rs, opts, t, false) {
+				srv.recordFire(rs, t)
+			}
+			from = t
+		}
+		planned[rs] = from
+	}
+}
+
+// scheduleLocation returns the time zone the cron spec is evaluated in:
+// the time zone of the CRON_TZ= prefix, or the scheduler location.
+func (srv *SchedulerServer) scheduleLocation(cronSpec string) (*time.Location, error) {
+	if name := cronSpecTimeZone(cronSpec); name != "" {
+		return loadLocation(name)
+	}
+	if srv.cnf.Location != nil {
+		return srv.cnf.Location, nil
+	}
+	return time.UTC, nil
+}
+
+// schedule schedules the task of the given task scheduler and returns the scheduler entry ID.
+// The task payload is taken from the scheduler if it implements TaskPayloader.
+func (srv *SchedulerServer) schedule(scheduler TaskScheduler) (string, error) {
+	var payload any
+	if p, ok := scheduler.(TaskPayloader); ok {
+		payload = p.Payload()
+	}
+	return srv.register(scheduler.Schedule(), scheduler.TaskName(), payload, scheduler.Options())
+}
+
+// log logs the message with the scheduler logger if the level is enabled.
+func (srv *SchedulerServer) log(level asynq.LogLevel, msg string) {
+	if level < srv.cnf.LogLevel {
+		return
+	}
+	switch level {
+	case asynq.DebugLevel:
+		srv.cnf.Logger.Debug(msg)
+	case asynq.InfoLevel:
+		srv.cnf.Logger.Info(msg)
+	case asynq.WarnLevel:
+		srv.cnf.Logger.Warn(msg)
+	default:
+		srv.cnf.Logger.Error(msg)
+	}
+}
+
 // Run runs the scheduler with the provided handlers.
 // It returns a function that can be used to run server in a error group.
 // E.g.:
 //
 //	eg, ctx := errgroup.WithContext(context.Background())
 //	eg.Go(schedulerServer.Run())
+//
+// The function returns an error if the scheduler fails to start.
+// In strict mode (see SetStrictOptions), it also returns an error if any scheduler option is invalid.
+// The scheduler runs until it receives a termination signal or Shutdown is called.
 func (srv *SchedulerServer) Run() func() error {
 	return func() error {
-		// Run scheduler
-		if err := srv.asynq.Run(); err != nil {
-			return errors.Join(ErrFailedToStartSchedulerServer, err)
+		select {
+		case <-srv.done:
+			return errors.Join(ErrFailedToStartSchedulerServer, ErrSchedulerIsShutDown)
+		default:
 		}
+
+		srv.mu.Lock()
+		strict := srv.strict
+		srv.mu.Unlock()
+		if strict && srv.optsErr != nil {
+			return errors.Join(ErrFailedToStartSchedulerServer, srv.optsErr)
+		}
+
+		// Start scheduler, or let the leader election start it.
+		if srv.leader == nil {
+			if err := srv.start(); err != nil {
+				return errors.Join(ErrFailedToStartSchedulerServer, err)
+			}
+		} else {
+			srv.wg.Add(1)
+			go srv.runLeaderElection()
+		}
+		srv.wg.Add(1)
+		go srv.runPlanner()
+
+		// Wait for a termination signal or shutdown
+		waitForSignals(srv.done, func() {})
+		srv.Shutdown()
+
 		return nil
 	}
 }
@@ -74,7 +610,14 @@ func (srv *SchedulerServer) Run() func() error {
 // Shutdown gracefully shuts down the scheduler server by waiting for all
 // pending tasks to be processed.
 func (srv *SchedulerServer) Shutdown() {
-	srv.asynq.Shutdown()
+	srv.once.Do(func() { close(srv.done) })
+	srv.wg.Wait()
+	srv.stop()
+
+	// Let another instance take over right away.
+	if srv.leader != nil {
+		srv.resign()
+	}
 }
 
 // RunSchedulerServer runs the scheduler server with the given Redis connection string,
@@ -116,7 +659,7 @@ func RunSchedulerServer(ctx context.Context, redisClient redis.UniversalClient,
 
 		// Register schedulers
 		for _, scheduler := range schedulers {
-			if err := srv.ScheduleTask(scheduler.Schedule(), scheduler.TaskName(), scheduler.Options()...); err != nil {
+			if _, err := srv.schedule(scheduler); err != nil {
 				return errors.Join(ErrFailedToRunSchedulerServer, err)
 			}
 		}
@@ -0,0 +1,78 @@
+package asyncer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// jitterOption is the option to delay the tasks of a schedule by a random duration.
+// The runs of the schedule are planned by the server, so every run gets a new delay (see SchedulerServer).
+type jitterOption struct {
+	max time.Duration
+}
+
+// String returns the string representation of the option.
+func (o jitterOption) String() string { return fmt.Sprintf("Jitter(%v)", o.max) }
+
+// Type returns the type of the option.
+func (o jitterOption) Type() asynq.OptionType { return jitterOpt }
+
+// Value returns the value of the option.
+func (o jitterOption) Value() any { return o.max }
+
+// spreadOption is the option to delay the tasks of a schedule by a deterministic duration.
+// The delay is derived from the hash of the schedule ID (see ScheduleID), so it's the same on every fire
+// and on every scheduler instance, while different schedules are spread evenly across the window.
+type spreadOption struct {
+	window time.Duration
+}
+
+// String returns the string representation of the option.
+func (o spreadOption) String() string { return fmt.Sprintf("Spread(%v)", o.window) }
+
+// Type returns the type of the option.
+func (o spreadOption) Type() asynq.OptionType { return spreadOpt }
+
+// Value returns the value of the option.
+func (o spreadOption) Value() any { return o.window }
+
+// Jitter delays every task of a schedule by a random duration up to the given maximum,
+// so schedules firing at the same instant don't hit shared resources at once. It overrides ProcessIn and ProcessAt.
+func Jitter(maxDelay time.Duration) TaskOption {
+	if maxDelay <= 0 {
+		return invalid(nil, "jitter must be positive, got %v", maxDelay)
+	}
+	return jitterOption{max: maxDelay}
+}
+
+// Spread delays every task of a schedule by a fixed duration within the given window, different per schedule.
+// It's added up with Jitter and overrides ProcessIn and ProcessAt.
+func Spread(window time.Duration) TaskOption {
+	if window <= 0 {
+		return invalid(nil, "spread window must be positive, got %v", window)
+	}
+	return spreadOption{window: window}
+}
+
+// fireDelay returns the processing delay of a task of the schedule set by the Jitter and Spread options.
+func fireDelay(rs *registeredSchedule) time.Duration {
+	var delay time.Duration
+	if opt, ok := findOption[spreadOption](rs.opts); ok {
+		delay += spreadDelay(rs.id, opt.window)
+	}
+	if opt, ok := findOption[jitterOption](rs.opts); ok {
+		delay += rand.N(opt.max)
+	}
+	return delay
+}
+
+// spreadDelay returns the deterministic delay of the schedule with the given ID within the window.
+func spreadDelay(id string, window time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return time.Duration(h.Sum64() % uint64(window))
+}
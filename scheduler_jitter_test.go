@@ -0,0 +1,65 @@
+package asyncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestJitteredSchedulesArePlanned(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+	srv := NewSchedulerServer(rdb)
+
+	tests := []struct {
+		name        string
+		opts        []TaskOption
+		wantPlanned bool
+	}{
+		{name: "plain", opts: nil},
+		{name: "spread", opts: []TaskOption{Spread(time.Minute)}},
+		{name: "jitter", opts: []TaskOption{Jitter(time.Minute)}, wantPlanned: true},
+		{name: "jitter and spread", opts: []TaskOption{Jitter(time.Minute), Spread(time.Minute)}, wantPlanned: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entryID, err := srv.register("@every 1h", "test:"+tt.name, nil, tt.opts)
+			if err != nil {
+				t.Fatalf("register() error = %v", err)
+			}
+			rs := srv.schedules[entryID]
+			if planned := rs.spec == ""; planned != tt.wantPlanned {
+				t.Errorf("planned by the server = %v, want %v", planned, tt.wantPlanned)
+			}
+		})
+	}
+}
+
+func TestFireDelay(t *testing.T) {
+	spread := &registeredSchedule{id: "sync:abc", opts: []asynq.Option{Spread(time.Minute)}}
+	first := fireDelay(spread)
+	if first < 0 || first >= time.Minute {
+		t.Fatalf("fireDelay() = %v, want within [0, 1m)", first)
+	}
+	for range 10 {
+		if d := fireDelay(spread); d != first {
+			t.Fatalf("fireDelay() = %v, want the same spread delay %v on every fire", d, first)
+		}
+	}
+
+	jitter := &registeredSchedule{id: "sync:abc", opts: []asynq.Option{Jitter(time.Hour)}}
+	delays := make(map[time.Duration]bool)
+	for range 10 {
+		d := fireDelay(jitter)
+		if d < 0 || d >= time.Hour {
+			t.Fatalf("fireDelay() = %v, want within [0, 1h)", d)
+		}
+		delays[d] = true
+	}
+	if len(delays) < 2 {
+		t.Errorf("fireDelay() returned the same jitter delay on every fire")
+	}
+}
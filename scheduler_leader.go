@@ -0,0 +1,254 @@
+package asyncer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Default leader election options.
+const (
+	defaultLeaderElectionName = "default"        // Default name of the scheduler group
+	defaultLeaderLeaseTTL     = 15 * time.Second // Default leader lease TTL
+	minLeaderLeaseTTL         = time.Second      // Minimum leader lease TTL
+)
+
+type (
+	// LeaderStatus describes the leader election state of a scheduler instance.
+	// It can be exported as metrics, e.g. to alert when no instance is the leader.
+	LeaderStatus struct {
+		// Enabled reports whether the leader election is enabled.
+		// Without it, every instance enqueues the scheduled tasks.
+		Enabled bool
+		// InstanceID is the identifier of the scheduler instance in the election.
+		InstanceID string
+		// IsLeader reports whether the instance is the leader.
+		IsLeader bool
+		// LeaderSince is the time the instance became the leader, zero if it's not the leader.
+		LeaderSince time.Time
+		// Elections is the number of times the instance became the leader.
+		Elections int
+		// LastRenewal is the time the last successful request to acquire or renew the lease was sent,
+		// which the lease expiry is counted from.
+		LastRenewal time.Time
+		// RenewalFailures is the number of failed attempts to acquire or renew the lease.
+		RenewalFailures int
+	}
+
+	// leaderElection is the leader election state of the scheduler instance.
+	leaderElection struct {
+		mu     sync.Mutex
+		name   string
+		ttl    time.Duration
+		status LeaderStatus
+	}
+)
+
+// campaignScript acquires the leader lease if it's free, or renews it if it's owned by the instance.
+var campaignScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// resignScript releases the leader lease owned by the instance and notifies the other instances.
+// The channel is passed as an argument, so the lease is the only key and the script runs on a redis cluster.
+var resignScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	redis.call("PUBLISH", ARGV[2], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// EnableLeaderElection enables the leader election between the scheduler instances with the same name,
+// so only one of them enqueues the scheduled tasks at a time.
+// The asynq scheduler runs on the leader only, it's started when the instance becomes the leader
+// and shut down when the instance loses the leadership.
+// The leader holds a lease in redis with the given TTL and renews it every third of the TTL.
+// If the leader shuts down, another instance takes over immediately,
+// if it crashes, another instance takes over once the lease expires.
+// The new leader catches up the runs missed in between, according to the misfire policies (see MisfireRunOnce).
+// Empty name uses the default scheduler group, zero TTL uses the default 15 seconds,
+// and the TTL is at least one second.
+// It must be called before Run, e.g.:
+//
+//	schedulerServer := asyncer.NewSchedulerServer(redisClient)
+//	schedulerServer.EnableLeaderElection("billing", 15*time.Second)
+func (srv *SchedulerServer) EnableLeaderElection(name string, ttl time.Duration) {
+	if name == "" {
+		name = defaultLeaderElectionName
+	}
+	if ttl == 0 {
+		ttl = defaultLeaderLeaseTTL
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.leader = newLeaderElection(name, max(ttl, minLeaderLeaseTTL))
+}
+
+// newLeaderElection returns the leader election state of a new scheduler instance.
+func newLeaderElection(name string, ttl time.Duration) *leaderElection {
+	hostname, _ := os.Hostname()
+	return &leaderElection{
+		name: name,
+		ttl:  ttl,
+		status: LeaderStatus{
+			Enabled:    true,
+			InstanceID: fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), strconv.FormatInt(time.Now().UnixNano(), 36)),
+		},
+	}
+}
+
+// LeaderStatus returns the leader election state of the scheduler instance.
+func (srv *SchedulerServer) LeaderStatus() LeaderStatus {
+	if srv.leader == nil {
+		return LeaderStatus{IsLeader: true}
+	}
+
+	srv.leader.mu.Lock()
+	defer srv.leader.mu.Unlock()
+
+	status := srv.leader.status
+	status.IsLeader = srv.leader.holdsLease()
+	return status
+}
+
+// isLeader reports whether the scheduler instance should enqueue the scheduled tasks.
+func (srv *SchedulerServer) isLeader() bool {
+	if srv.leader == nil {
+		return true
+	}
+
+	srv.leader.mu.Lock()
+	defer srv.leader.mu.Unlock()
+
+	return srv.leader.holdsLease()
+}
+
+// holdsLease reports whether the instance is the leader and its lease is not expired yet,
+// e.g. because the renewals are stuck on a slow redis.
+// The caller must hold the mutex.
+func (le *leaderElection) holdsLease() bool {
+	return le.status.IsLeader && time.Since(le.status.LastRenewal) < le.ttl
+}
+
+// runLeaderElection campaigns for the leader lease until the scheduler is shut down.
+// The lease is checked every third of its TTL, and immediately when the leader resigns.
+func (srv *SchedulerServer) runLeaderElection() {
+	defer srv.wg.Done()
+
+	pubsub := srv.redis.Subscribe(context.Background(), srv.leader.channel())
+	defer pubsub.Close()
+
+	ticker := time.NewTicker(srv.leader.ttl / 3)
+	defer ticker.Stop()
+
+	ch := pubsub.Channel()
+	for {
+		srv.campaign()
+
+		select {
+		case <-srv.done:
+			return
+		case <-ticker.C:
+		case <-ch:
+		}
+	}
+}
+
+// campaign acquires or renews the leader lease and updates the leadership state.
+// The instance steps down if the lease can't be renewed, so two instances are never leaders at once.
+// The asynq scheduler is started on the leader and shut down on the other instances.
+func (srv *SchedulerServer) campaign() {
+	le := srv.leader
+	ctx, cancel := context.WithTimeout(context.Background(), le.ttl/3)
+	defer cancel()
+
+	// The lease is counted from the request, since redis may have renewed it at any time before the reply.
+	start := time.Now()
+	acquired, err := campaignScript.Run(ctx, srv.redis, []string{le.key()}, le.status.InstanceID, le.ttl.Milliseconds()).Int()
+
+	le.mu.Lock()
+	wasLeader := le.status.IsLeader
+	switch {
+	case err != nil:
+		le.status.RenewalFailures++
+		le.status.IsLeader = false
+	case acquired == 1:
+		le.status.LastRenewal = start
+		le.status.IsLeader = true
+	default:
+		le.status.IsLeader = false
+	}
+	isLeader := le.status.IsLeader
+	if isLeader && !wasLeader {
+		le.status.Elections++
+		le.status.LeaderSince = time.Now()
+	}
+	if !isLeader {
+		le.status.LeaderSince = time.Time{}
+	}
+	le.mu.Unlock()
+
+	switch {
+	case err != nil:
+		srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: failed to campaign for scheduler leadership: %v", err))
+	case isLeader && !wasLeader:
+		srv.log(asynq.InfoLevel, fmt.Sprintf("asyncer: scheduler instance %s became the leader", le.status.InstanceID))
+	case !isLeader && wasLeader:
+		srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: scheduler instance %s lost the leadership", le.status.InstanceID))
+	}
+
+	if !isLeader {
+		srv.stop()
+		return
+	}
+	// The scheduler is started on every campaign until it succeeds.
+	// It catches up the runs missed while there was no leader.
+	if err := srv.start(); err != nil {
+		srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to start scheduler on the leader: %v", err))
+	}
+}
+
+// resign releases the leader lease, so another instance can take over immediately.
+func (srv *SchedulerServer) resign() {
+	le := srv.leader
+	ctx, cancel := context.WithTimeout(context.Background(), le.ttl/3)
+	defer cancel()
+
+	if err := resignScript.Run(ctx, srv.redis, []string{le.key()}, le.status.InstanceID, le.channel()).Err(); err != nil {
+		srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: failed to resign scheduler leadership: %v", err))
+	}
+
+	le.mu.Lock()
+	le.status.IsLeader = false
+	le.status.LeaderSince = time.Time{}
+	le.mu.Unlock()
+}
+
+// key returns the redis key of the leader lease.
+func (le *leaderElection) key() string {
+	return fmt.Sprintf("asyncer:scheduler:%s:leader", le.name)
+}
+
+// channel returns the redis channel of the leader resignations.
+func (le *leaderElection) channel() string {
+	return fmt.Sprintf("asyncer:scheduler:%s:leader:resigned", le.name)
+}
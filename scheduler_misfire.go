@@ -0,0 +1,128 @@
+package asyncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// lastRunsKey is the redis key of the last fire times of the schedules.
+const lastRunsKey = "asyncer:scheduler:last-runs"
+
+// misfireOption is the option to set the misfire policy of a schedule.
+// The missed runs are detected by the last fire time of the schedule stored in redis,
+// and are caught up when the scheduler starts, or when the schedule is registered in a running scheduler.
+type misfireOption struct {
+	runs int
+}
+
+// String returns the string representation of the option.
+func (o misfireOption) String() string { return fmt.Sprintf("Misfire(%d)", o.runs) }
+
+// Type returns the type of the option.
+func (o misfireOption) Type() asynq.OptionType { return misfireOpt }
+
+// Value returns the value of the option.
+func (o misfireOption) Value() any { return o.runs }
+
+// MisfireSkip sets the misfire policy of a schedule to skip the runs missed while no scheduler was running.
+// It's the default policy.
+func MisfireSkip() TaskOption {
+	return misfireOption{runs: 0}
+}
+
+// MisfireRunOnce sets the misfire policy of a schedule to enqueue a single task
+// if any run was missed while no scheduler was running, e.g. during a deploy.
+func MisfireRunOnce() TaskOption {
+	return misfireOption{runs: 1}
+}
+
+// MisfireRunAll sets the misfire policy of a schedule to enqueue a task for every missed run,
+// up to the given number of the earliest ones. Tasks with the Unique option are deduplicated to one.
+func MisfireRunAll(maxRuns int) TaskOption {
+	if maxRuns < 1 {
+		return invalid(misfireOption{runs: 1}, "misfire max runs must be positive, got %d", maxRuns)
+	}
+	return misfireOption{runs: maxRuns}
+}
+
+// setLastRunScript sets the last fire time of the schedule, unless the stored one is later, and returns the previous one.
+// It's atomic, so only one of the scheduler instances catches up the missed runs,
+// and the runs planned ahead of their fire times are not caught up again.
+var setLastRunScript = redis.NewScript(`
+local prev = redis.call("HGET", KEYS[1], ARGV[1])
+if not prev or tonumber(prev) < tonumber(ARGV[2]) then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+end
+return prev
+`)
+
+// catchUp enqueues the tasks for the runs of the schedule missed since its last fire,
+// according to the misfire policy of the schedule.
+// The first registration of the schedule just starts tracking its fire times.
+// It's called when the scheduler starts and when a schedule is registered in a running scheduler,
+// and does nothing unless the instance is the leader (see SchedulerServer.EnableLeaderElection).
+func (srv *SchedulerServer) catchUp(rs *registeredSchedule) {
+	if rs.misfire < 1 || !srv.active() {
+		return
+	}
+
+	now := time.Now()
+	prev, err := setLastRunScript.Run(context.Background(), srv.redis, []string{lastRunsKey}, rs.id, now.Unix()).Int64()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			srv.log(asynq.ErrorLevel, fmt.Sprintf("asyncer: failed to catch up missed runs of scheduled task %q: %v", rs.taskName, err))
+		}
+		return
+	}
+
+	for _, run := range missedRuns(rs, time.Unix(prev, 0), now) {
+		srv.log(asynq.InfoLevel, fmt.Sprintf("asyncer: catching up missed run of scheduled task %q at %s", rs.taskName, run))
+		opts := rs.opts
+		if delay := fireDelay(rs); delay > 0 {
+			opts = append(slices.Clone(opts), asynq.ProcessIn(delay))
+		}
+		srv.enqueue(rs, opts, run, true)
+	}
+}
+
+// recordFire stores the fire time of the schedule, if it catches up missed runs.
+// It's called by the asynq.Scheduler hooks and the planner (see SchedulerServer).
+// An instance whose leader lease expired doesn't store the fire time, so it can't hide the runs missed by the new leader.
+func (srv *SchedulerServer) recordFire(rs *registeredSchedule, t time.Time) {
+	if rs.misfire < 1 || !srv.isLeader() {
+		return
+	}
+	if err := setLastRunScript.Run(context.Background(), srv.redis, []string{lastRunsKey}, rs.id, t.Unix()).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		srv.log(asynq.WarnLevel, fmt.Sprintf("asyncer: failed to record fire of scheduled task %q: %v", rs.taskName, err))
+	}
+}
+
+// missedRuns returns the fire times of the schedule after the last run and before now,
+// limited by the misfire policy of the schedule.
+func missedRuns(rs *registeredSchedule, last, now time.Time) []time.Time {
+	var runs []time.Time
+	for t := rs.schedule.Next(last.In(rs.location)); !t.IsZero() && t.Before(now) && len(runs) < rs.misfire; t = rs.schedule.Next(t) {
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// scheduleID returns the stable identifier of the schedule,
+// derived from the task name, the cron spec and the payload.
+func scheduleID(taskName, cronSpec string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(taskName))
+	h.Write([]byte{0})
+	h.Write([]byte(cronSpec))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return taskName + ":" + hex.EncodeToString(h.Sum(nil))[:16]
+}
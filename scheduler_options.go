@@ -1,11 +1,14 @@
 package asyncer
 
-import "github.com/hibiken/asynq"
+import (
+	"github.com/hibiken/asynq"
+)
 
 // WithSchedulerLogLevel sets the scheduler log level.
+// Unknown levels fall back to the info level.
 func WithSchedulerLogLevel(level string) SchedulerServerOption {
 	return func(cnf *asynq.SchedulerOpts) {
-		cnf.LogLevel = castToAsynqLogLevel(level)
+		cnf.LogLevel = asynqLogLevel(level)
 	}
 }
 
@@ -19,10 +22,12 @@ func WithSchedulerLogger(logger asynq.Logger) SchedulerServerOption {
 }
 
 // WithSchedulerLocation sets the scheduler location.
+// It's used by the schedules without their own time zone (see TimeZone).
+// Unknown time zones fall back to UTC.
 func WithSchedulerLocation(timeZone string) SchedulerServerOption {
 	return func(cnf *asynq.SchedulerOpts) {
-		// parse location from string and set it to the config
-		cnf.Location = parseLocation(timeZone)
+		// An unknown location is left unset, so the server reports it in strict mode before falling back to UTC.
+		cnf.Location, _ = loadLocation(timeZone)
 	}
 }
 
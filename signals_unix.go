@@ -0,0 +1,32 @@
+//go:build unix
+
+package asyncer
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitForSignals waits for a termination signal or until the done channel is closed.
+// SIGTERM and SIGINT terminate the wait.
+// SIGTSTP calls the stop function to stop processing new tasks.
+func waitForSignals(done <-chan struct{}, stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, unix.SIGTERM, unix.SIGINT, unix.SIGTSTP)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-sigs:
+			if sig == unix.SIGTSTP {
+				stop()
+				continue
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package asyncer
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/windows"
+)
+
+// waitForSignals waits for a termination signal or until the done channel is closed.
+// SIGTERM and SIGINT terminate the wait.
+// Windows has no SIGTSTP, so the stop function is never called.
+func waitForSignals(done <-chan struct{}, _ func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, windows.SIGTERM, windows.SIGINT)
+	defer signal.Stop(sigs)
+
+	select {
+	case <-done:
+	case <-sigs:
+	}
+}
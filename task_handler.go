@@ -52,16 +52,20 @@ func (h *handlerFuncWrapper[Payload]) Schedule() string {
 // The payload is unmarshaled into a Payload struct, and if the unmarshaling fails, an error is returned.
 // Otherwise, the wrapped handler function is called with the context and unmarshaled payload.
 func (h *handlerFuncWrapper[Payload]) Handle(ctx context.Context, payload []byte) error {
-	var p Payload
-	if payload != nil {
-		if err := json.Unmarshal(payload, &p); err != nil {
-			return errors.Join(ErrFailedToUnmarshalPayload, err)
-		}
+	p, err := unmarshalPayload[Payload](payload)
+	if err != nil {
+		return err
 	}
 
 	return h.fn(ctx, p)
 }
 
+// decodePayload unmarshals the payload into the Payload type of the handler function.
+// It's used by the Inspector to decode the payloads of the registered task handlers.
+func (h *handlerFuncWrapper[Payload]) decodePayload(payload []byte) (any, error) {
+	return unmarshalPayload[Payload](payload)
+}
+
 // Options returns the options for the handler function.
 func (h *handlerFuncWrapper[Payload]) Options() []asynq.Option {
 	return h.opts
@@ -72,6 +76,7 @@ func (h *handlerFuncWrapper[Payload]) Options() []asynq.Option {
 // The name parameter represents the name of the handler, while the fn parameter is the actual handler function.
 // The TaskHandler returned by HandlerFunc is responsible for executing the handler function when a task of the specified payload type is received.
 // The payload type is specified using the generic type parameter Payload.
+// It also handles scheduled tasks created with NewTaskSchedulerWithPayload for the same payload type.
 func HandlerFunc[Payload any](name string, fn handlerFunc[Payload], opts ...TaskOption) TaskHandler {
 	return &handlerFuncWrapper[Payload]{
 		name: name,
@@ -79,3 +84,15 @@ func HandlerFunc[Payload any](name string, fn handlerFunc[Payload], opts ...Task
 		opts: opts,
 	}
 }
+
+// unmarshalPayload unmarshals the task payload into the Payload type.
+// An empty payload results in the zero value of the type.
+func unmarshalPayload[Payload any](payload []byte) (Payload, error) {
+	var p Payload
+	if payload != nil {
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return p, errors.Join(ErrFailedToUnmarshalPayload, err)
+		}
+	}
+	return p, nil
+}
@@ -6,13 +6,34 @@ import (
 	"github.com/hibiken/asynq"
 )
 
+// TaskOption is an option of a task or a task handler.
+// Invalid input (e.g. a non-positive timeout) is coerced to the closest valid value by asyncer,
+// use ValidateTaskOptions or the strict mode (e.g. WithStrictOptionsEnq) to catch invalid options.
+// Options with invalid input are not plain asynq options, asynq ignores them when they are passed to it directly.
 type TaskOption = asynq.Option
 
+// Custom option types.
+// Options of these types are handled by asyncer and ignored by asynq.
+const (
+	uniqueKeyOpt asynq.OptionType = iota + 100
+	concurrencyLimitOpt
+	rateLimitOpt
+	tenantConcurrencyOpt
+	invalidOpt
+	timeZoneOpt
+	misfireOpt
+	jitterOpt
+	spreadOpt
+	calendarOpt
+	scheduleIDOpt
+	tenantFairnessOpt
+)
+
 // MaxRetry sets the maximum number of retries for the task.
 // The task will be marked as failed after the specified number of failed attempts.
 func MaxRetry(n int) TaskOption {
 	if n < 0 {
-		n = 0
+		return invalid(asynq.MaxRetry(0), "max retry must not be negative, got %d", n)
 	}
 	return asynq.MaxRetry(n)
 }
@@ -21,7 +42,7 @@ func MaxRetry(n int) TaskOption {
 // The task will be marked as failed if it takes longer than the specified duration.
 func Timeout(d time.Duration) TaskOption {
 	if d <= 0 {
-		d = time.Second
+		return invalid(asynq.Timeout(time.Second), "timeout must be positive, got %v", d)
 	}
 	return asynq.Timeout(d)
 }
@@ -30,7 +51,7 @@ func Timeout(d time.Duration) TaskOption {
 // The task will not be processed if it is received after the specified date and time.
 func Deadline(t time.Time) TaskOption {
 	if t.IsZero() || t.Before(time.Now()) {
-		t = time.Now().Add(time.Second)
+		return invalid(asynq.Deadline(time.Now().Add(time.Second)), "deadline must be in the future, got %v", t)
 	}
 	return asynq.Deadline(t)
 }
@@ -39,9 +60,10 @@ func Deadline(t time.Time) TaskOption {
 // The task will not be enqueued if there is an identical task already in the queue.
 // The uniqueness constraint is based on the task type and payload.
 // The uniqueness constraint is valid for the specified duration.
+// To deduplicate tasks by a custom key instead of the payload, use UniqueKey.
 func Unique(ttl time.Duration) TaskOption {
-	if ttl <= 0 {
-		ttl = time.Second
+	if ttl < time.Second {
+		return invalid(asynq.Unique(time.Second), "unique ttl must be at least 1s, got %v", ttl)
 	}
 	return asynq.Unique(ttl)
 }
@@ -51,19 +73,19 @@ func Unique(ttl time.Duration) TaskOption {
 // Use this option to enqueue a task with a specific ID to prevent duplicate tasks.
 // If a task with the same ID already exists in the queue, it will be replaced by the new task.
 func TaskID(id string) TaskOption {
-	if id != "" {
-		return asynq.TaskID(id)
+	if id == "" {
+		return invalid(nil, "task id must not be empty")
 	}
-	return nil
+	return asynq.TaskID(id)
 }
 
 // Group returns an option to specify the group used for the task.
 // Tasks in a given queue with the same group will be aggregated into one task before passed to Handler.
 func Group(g string) TaskOption {
-	if g != "" {
-		return asynq.Group(g)
+	if g == "" {
+		return invalid(nil, "group must not be empty")
 	}
-	return nil
+	return asynq.Group(g)
 }
 
 // ProcessAt returns an option to specify when to process the given task.
@@ -71,7 +93,7 @@ func Group(g string) TaskOption {
 // If there's a conflicting ProcessIn option, the last option passed to Enqueue overrides the others.
 func ProcessAt(t time.Time) TaskOption {
 	if t.IsZero() || t.Before(time.Now()) {
-		t = time.Now().Add(time.Second)
+		return invalid(asynq.ProcessAt(time.Now().Add(time.Second)), "process at must be in the future, got %v", t)
 	}
 	return asynq.ProcessAt(t)
 }
@@ -81,7 +103,29 @@ func ProcessAt(t time.Time) TaskOption {
 // If there's a conflicting ProcessAt option, the last option passed to Enqueue overrides the others.
 func ProcessIn(d time.Duration) TaskOption {
 	if d <= 0 {
-		d = time.Second
+		return invalid(asynq.ProcessIn(time.Second), "process in must be positive, got %v", d)
 	}
 	return asynq.ProcessIn(d)
 }
+
+// ProcessAtSpec returns an option to process the given task at the next run of the schedule spec after now,
+// e.g. "@at 2026-12-01 09:00 Europe/Berlin" or "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;BYHOUR=9".
+// It accepts the same syntax as the scheduler (see ValidateCronSpec), specs without a time zone are evaluated in UTC.
+// An @at time in the past is invalid, the same as a past ProcessAt time.
+//
+// If there's a conflicting ProcessAt or ProcessIn option, the last option passed to Enqueue overrides the others.
+func ProcessAtSpec(spec string) TaskOption {
+	schedule, err := parseCronSpec(spec)
+	if err != nil {
+		return invalid(nil, "invalid process at spec: %v", err)
+	}
+	now := time.Now().UTC()
+	next := schedule.Next(now)
+	if next.IsZero() {
+		if _, ok := schedule.(*atSchedule); ok {
+			return invalid(asynq.ProcessAt(now.Add(time.Second)), "process at spec %q is in the past", spec)
+		}
+		return invalid(nil, "process at spec %q has no run after now", spec)
+	}
+	return asynq.ProcessAt(next)
+}
@@ -16,10 +16,18 @@ type (
 		// Schedule returns the cron spec for the task.
 		// For more information about cron spec, see https://pkg.go.dev/github.com/robfig/cron/v3#hdr-CRON_Expression_Format.
 		Schedule() string
-		// Options returns the options for the task scheduler.
+		// Options returns the task and schedule options for the task scheduler (see SchedulerServer).
 		Options() []TaskOption
 	}
 
+	// TaskPayloader is an optional interface of task schedulers.
+	// Tasks of the schedulers implementing it are enqueued with the returned payload encoded as JSON,
+	// so they can be handled by a handler created with HandlerFunc.
+	TaskPayloader interface {
+		// Payload returns the payload of the scheduled task.
+		Payload() any
+	}
+
 	// scheduledHandlerFunc is a function that handles a scheduled task.
 	scheduledHandlerFunc func(context.Context) error
 
@@ -31,6 +39,15 @@ type (
 		fn       scheduledHandlerFunc
 		opts     []asynq.Option
 	}
+
+	// scheduledPayloadTaskWrapper is a struct that represents a scheduled task with a payload.
+	// It implements the TaskScheduler and TaskPayloader interfaces.
+	scheduledPayloadTaskWrapper[Payload any] struct {
+		cronSpec string
+		name     string
+		payload  Payload
+		opts     []asynq.Option
+	}
 )
 
 // TaskName returns the name of the task handled by the scheduledTaskWrapper.
@@ -60,6 +77,41 @@ func NewTaskScheduler(cronSpec, name string, opts ...TaskOption) TaskScheduler {
 	return &scheduledTaskWrapper{cronSpec: cronSpec, name: name, opts: opts}
 }
 
+// NewTaskSchedulerWithPayload creates a new task scheduler with the given cron spec, name and payload.
+// The scheduled tasks are handled by a handler created with HandlerFunc for the same payload type,
+// so the same handler can serve multiple schedules with different payloads.
+// E.g.:
+//
+//	asyncer.NewTaskSchedulerWithPayload("0 8 * * *", "report", ReportPayload{Period: "daily"})
+//	asyncer.NewTaskSchedulerWithPayload("0 8 * * 1", "report", ReportPayload{Period: "weekly"})
+//
+//	asyncer.HandlerFunc("report", func(ctx context.Context, payload ReportPayload) error {
+//		// ... build the report for payload.Period ...
+//	})
+func NewTaskSchedulerWithPayload[Payload any](cronSpec, name string, payload Payload, opts ...TaskOption) TaskScheduler {
+	return &scheduledPayloadTaskWrapper[Payload]{cronSpec: cronSpec, name: name, payload: payload, opts: opts}
+}
+
+// TaskName returns the name of the scheduled task.
+func (h *scheduledPayloadTaskWrapper[Payload]) TaskName() string {
+	return h.name
+}
+
+// Schedule returns the cron specification for the task scheduler.
+func (h *scheduledPayloadTaskWrapper[Payload]) Schedule() string {
+	return h.cronSpec
+}
+
+// Payload returns the payload of the scheduled task.
+func (h *scheduledPayloadTaskWrapper[Payload]) Payload() any {
+	return h.payload
+}
+
+// Options returns the options for the task scheduler.
+func (h *scheduledPayloadTaskWrapper[Payload]) Options() []asynq.Option {
+	return h.opts
+}
+
 // ScheduledHandlerFunc is a function that creates a TaskHandler for a scheduled task.
 // It takes a name string and a scheduledHandlerFunc as parameters and returns a TaskHandler.
 // The name parameter specifies the name of the scheduled task, while the fn parameter is the function to be executed when the task is triggered.
@@ -0,0 +1,241 @@
+package asyncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// tenantConcurrencyOption is a handler option that limits
+	// the number of concurrently processed tasks per tenant across all queue servers.
+	// It is handled by the queue server and ignored by asynq.
+	tenantConcurrencyOption struct {
+		field  string
+		limit  int
+		limits map[string]int
+	}
+
+	// tenantFairnessOption is a handler option that dispatches the tasks
+	// round-robin across tenants, weighted per tenant, across all queue servers.
+	// It is handled by the queue server and ignored by asynq.
+	tenantFairnessOption struct {
+		field   string
+		weights map[string]int
+	}
+)
+
+// tenantFairnessWindow is the time a tenant competes for the fair share after its last task was seen.
+// Tenants without tasks for longer are not waited for.
+const tenantFairnessWindow = 3 * time.Second
+
+// tenantFairnessScript charges the tenant for a processed task, unless the tenant is ahead of its fair share.
+// Every tenant has a pass which grows by the stride (the inverse of its weight) with every processed task.
+// The task is processed if the pass of its tenant is less than one stride of a weight-one tenant
+// ahead of the lowest pass among the tenants seen within the window, i.e. the tenants with pending tasks.
+// A tenant seen for the first time starts at the lowest pass, so it doesn't catch up on its idle time.
+//
+// KEYS[1] -> passes of the tenants
+// KEYS[2] -> last seen times of the tenants
+// ARGV[1] -> tenant
+// ARGV[2] -> current time in milliseconds
+// ARGV[3] -> window in milliseconds
+// ARGV[4] -> stride of the tenant
+//
+// Returns 1 if the task can be processed, 0 otherwise.
+var tenantFairnessScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+for _, tenant in ipairs(redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", "(" .. (now - tonumber(ARGV[3])))) do
+	redis.call("ZREM", KEYS[2], tenant)
+	redis.call("ZREM", KEYS[1], tenant)
+end
+redis.call("ZADD", KEYS[2], now, ARGV[1])
+
+local lowest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local pass = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not pass then
+	pass = lowest[2] or 0
+	redis.call("ZADD", KEYS[1], pass, ARGV[1])
+end
+if lowest[2] and tonumber(pass) >= tonumber(lowest[2]) + 1 then
+	return 0
+end
+
+redis.call("ZINCRBY", KEYS[1], ARGV[4], ARGV[1])
+for _, key in ipairs(KEYS) do
+	redis.call("PEXPIRE", key, ARGV[3])
+end
+return 1
+`)
+
+// TenantConcurrency limits the number of tasks of the handler processed concurrently per tenant
+// across all queue servers sharing the same redis, so one noisy tenant can't occupy all workers.
+// The tenant key is taken from the given top-level field of the JSON payload.
+// The limit applies to every tenant, unless it's overridden for the tenant in the limits map,
+// which allows to give some tenants a bigger share of the workers.
+// Tasks exceeding the limit are requeued with a short delay without consuming a retry (see MaxConcurrency).
+// It only caps the tenants, combine it with TenantFairness to share the workers fairly between them.
+// Tasks without the tenant key are not limited.
+// It's a handler option, e.g.:
+//
+//	asyncer.HandlerFunc("report:generate", reportHandler,
+//		asyncer.TenantConcurrency("tenant_id", 2, map[string]int{"enterprise-tenant": 10}),
+//	)
+func TenantConcurrency(field string, limit int, limits map[string]int) TaskOption {
+	if field == "" {
+		return invalid(nil, "tenant field must not be empty")
+	}
+	if limit < 1 {
+		return invalid(
+			tenantConcurrencyOption{field: field, limit: 1, limits: limits},
+			"tenant concurrency must be positive, got %d", limit,
+		)
+	}
+	return tenantConcurrencyOption{field: field, limit: limit, limits: limits}
+}
+
+// String returns the string representation of the option.
+func (o tenantConcurrencyOption) String() string {
+	return fmt.Sprintf("TenantConcurrency(%q, %d)", o.field, o.limit)
+}
+
+// Type returns the type of the option.
+func (o tenantConcurrencyOption) Type() asynq.OptionType { return tenantConcurrencyOpt }
+
+// Value returns the value of the option.
+func (o tenantConcurrencyOption) Value() any { return o.field }
+
+// tenantLimit returns the concurrency limit for the given tenant.
+func (o tenantConcurrencyOption) tenantLimit(tenant string) int {
+	if limit, ok := o.limits[tenant]; ok && limit > 0 {
+		return limit
+	}
+	return o.limit
+}
+
+// tenantConcurrencyLimit returns a middleware which limits the number of concurrently processed tasks per tenant.
+func (srv *QueueServer) tenantConcurrencyLimit(taskName string, opt tenantConcurrencyOption, next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		tenant := tenantFromPayload(t.Payload(), opt.field)
+		if tenant == "" {
+			return next.ProcessTask(ctx, t)
+		}
+
+		return srv.concurrencyLimit(
+			tenantConcurrencyKey(taskName, tenant),
+			opt.tenantLimit(tenant),
+			next,
+		).ProcessTask(ctx, t)
+	})
+}
+
+// TenantFairness dispatches the tasks of the handler round-robin across tenants,
+// across all queue servers sharing the same redis, so one noisy tenant can't starve the others.
+// The tenant key is taken from the given top-level field of the JSON payload.
+// Every tenant gets an equal share of the processed tasks, unless its weight is set in the weights map,
+// e.g. a tenant with weight 3 gets three tasks processed per task of a tenant with the default weight 1.
+// Tasks of a tenant ahead of its share are requeued with a short delay, so the workers pick up the tasks of other tenants.
+// Tenants without tasks for a few seconds don't hold the others back. Tasks without the tenant key are not limited.
+// It's a handler option, e.g.:
+//
+//	asyncer.HandlerFunc("report:generate", reportHandler,
+//		asyncer.TenantFairness("tenant_id", map[string]int{"enterprise-tenant": 3}),
+//		asyncer.TenantConcurrency("tenant_id", 2, nil),
+//	)
+func TenantFairness(field string, weights map[string]int) TaskOption {
+	if field == "" {
+		return invalid(nil, "tenant field must not be empty")
+	}
+	for tenant, weight := range weights {
+		if weight < 1 {
+			return invalid(
+				tenantFairnessOption{field: field, weights: weights},
+				"tenant %q weight must be positive, got %d", tenant, weight,
+			)
+		}
+	}
+	return tenantFairnessOption{field: field, weights: weights}
+}
+
+// String returns the string representation of the option.
+func (o tenantFairnessOption) String() string {
+	return fmt.Sprintf("TenantFairness(%q, %v)", o.field, o.weights)
+}
+
+// Type returns the type of the option.
+func (o tenantFairnessOption) Type() asynq.OptionType { return tenantFairnessOpt }
+
+// Value returns the value of the option.
+func (o tenantFairnessOption) Value() any { return o.field }
+
+// stride returns the pass increment of the tenant for a processed task, the inverse of its weight.
+// Non-positive weights are treated as the default weight 1.
+func (o tenantFairnessOption) stride(tenant string) float64 {
+	if weight, ok := o.weights[tenant]; ok && weight > 0 {
+		return 1 / float64(weight)
+	}
+	return 1
+}
+
+// tenantFairness returns a middleware which processes the tasks of the tenants according to their fair shares.
+func (srv *QueueServer) tenantFairness(taskName string, opt tenantFairnessOption, next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		tenant := tenantFromPayload(t.Payload(), opt.field)
+		if tenant == "" {
+			return next.ProcessTask(ctx, t)
+		}
+
+		ok, err := tenantFairnessScript.Run(ctx, srv.redis,
+			tenantFairnessKeys(taskName),
+			tenant, time.Now().UnixMilli(), tenantFairnessWindow.Milliseconds(), opt.stride(tenant),
+		).Bool()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return srv.requeue(ctx, t, defaultRequeueDelay, ErrTenantFairShareExceeded)
+		}
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// tenantFromPayload returns the tenant key stored in the given top-level field of the JSON payload.
+// It returns an empty string if the payload is not a JSON object or the field is missing.
+func tenantFromPayload(payload []byte, field string) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return ""
+	}
+
+	raw, ok := fields[field]
+	if !ok || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+
+	var tenant string
+	if err := json.Unmarshal(raw, &tenant); err == nil {
+		return tenant
+	}
+
+	// Non-string keys (e.g. numeric IDs) are used as is.
+	return string(raw)
+}
+
+// tenantConcurrencyKey returns the redis key of the tenant concurrency semaphore.
+func tenantConcurrencyKey(taskName, tenant string) string {
+	return fmt.Sprintf("asyncer:concurrency:%s:tenant:%s", taskName, tenant)
+}
+
+// tenantFairnessKeys returns the redis keys of the tenant fair shares of the task:
+// the passes and the last seen times of the tenants.
+// The keys share the hash tag of the task name, so the script can use them together on a redis cluster.
+func tenantFairnessKeys(taskName string) []string {
+	key := fmt.Sprintf("asyncer:fairness:{%s}", taskName)
+	return []string{key + ":passes", key + ":seen"}
+}
@@ -0,0 +1,75 @@
+package asyncer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTenantFairness(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		weights map[string]int
+		wantErr bool
+	}{
+		{
+			name:  "default weights",
+			field: "tenant_id",
+		},
+		{
+			name:    "custom weights",
+			field:   "tenant_id",
+			weights: map[string]int{"enterprise": 3},
+		},
+		{
+			name:    "empty field",
+			field:   "",
+			wantErr: true,
+		},
+		{
+			name:    "non-positive weight",
+			field:   "tenant_id",
+			weights: map[string]int{"enterprise": 0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaskOptions(TenantFairness(tt.field, tt.weights))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTaskOptions(TenantFairness()) error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTenantFairnessStride(t *testing.T) {
+	opt := tenantFairnessOption{field: "tenant_id", weights: map[string]int{"enterprise": 4, "broken": 0}}
+
+	tests := []struct {
+		tenant string
+		want   float64
+	}{
+		{tenant: "enterprise", want: 0.25},
+		{tenant: "free", want: 1},
+		{tenant: "broken", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			if got := opt.stride(tt.tenant); got != tt.want {
+				t.Errorf("stride(%q) = %v, want %v", tt.tenant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantFairnessKeysShareHashTag(t *testing.T) {
+	keys := tenantFairnessKeys("report:generate")
+	for _, key := range keys {
+		if want := "asyncer:fairness:{report:generate}:"; !strings.HasPrefix(key, want) {
+			t.Errorf("tenantFairnessKeys() = %v, want the keys to share the %q prefix", keys, want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package asyncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// UniqueKeyer is an interface for payloads which provide their own uniqueness key.
+	// Tasks with such payloads are deduplicated by the task name and the returned key
+	// instead of the task name and the entire payload.
+	UniqueKeyer interface {
+		UniqueKey() string
+	}
+
+	// uniqueKeyOption is a task option that sets a custom uniqueness key for the task.
+	// It is handled by the enqueuer and ignored by asynq.
+	uniqueKeyOption struct {
+		key string
+		ttl time.Duration
+	}
+)
+
+// uniqueKeyTag is the struct tag used to mark payload fields which make up the uniqueness key.
+// E.g.:
+//
+//	type Payload struct {
+//		UserID    int64     `json:"user_id" asyncer:"unique"`
+//		CreatedAt time.Time `json:"created_at"`
+//	}
+const uniqueKeyTag = "asyncer"
+
+// UniqueKey sets a custom uniqueness key for the task.
+// The task will not be enqueued if a task with the same name and key was enqueued within the specified duration,
+// unless that task already succeeded.
+// Without the redis client (see WithRedisClientEnq), the key is used as the task ID instead,
+// so the task will not be enqueued while a task with the same name and key exists in the queue,
+// including the archived and retained tasks, regardless of the duration.
+// It takes precedence over the key provided by the payload (see UniqueKeyer).
+func UniqueKey(key string, ttl time.Duration) TaskOption {
+	if key == "" {
+		return invalid(nil, "unique key must not be empty")
+	}
+	if ttl <= 0 {
+		return invalid(uniqueKeyOption{key: key, ttl: time.Second}, "unique key ttl must be positive, got %v", ttl)
+	}
+	return uniqueKeyOption{key: key, ttl: ttl}
+}
+
+// String returns the string representation of the option.
+func (o uniqueKeyOption) String() string { return fmt.Sprintf("UniqueKey(%q, %v)", o.key, o.ttl) }
+
+// Type returns the type of the option.
+func (o uniqueKeyOption) Type() asynq.OptionType { return uniqueKeyOpt }
+
+// Value returns the value of the option.
+func (o uniqueKeyOption) Value() any { return o.key }
+
+// uniqueKeyFromPayload returns the uniqueness key provided by the payload.
+// The key is taken from the UniqueKey method if the payload implements UniqueKeyer,
+// otherwise it is composed of the struct fields tagged with `asyncer:"unique"`.
+// It returns an empty string if the payload doesn't provide a key.
+func uniqueKeyFromPayload(payload any) string {
+	if k, ok := payload.(UniqueKeyer); ok {
+		return k.UniqueKey()
+	}
+
+	v := reflect.ValueOf(payload)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	var parts []string
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get(uniqueKeyTag) != "unique" {
+			continue
+		}
+		parts = append(parts, uniqueKeyPart(v.Field(i)))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// uniqueKeyPart returns the uniqueness key part of the payload field.
+// Pointers are dereferenced and non-string values are JSON-encoded,
+// so equal payloads produce equal keys regardless of addresses and map ordering.
+func uniqueKeyPart(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	// JSON strings, e.g. encoded times, are used without quotes.
+	var s string
+	if data[0] == '"' && json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	return string(data)
+}
+
+// uniqueLockKeyPrefix is the prefix of the redis keys of the uniqueness locks.
+const uniqueLockKeyPrefix = "asyncer:{"
+
+// releaseUniqueLockScript releases the uniqueness lock if it's held by the task.
+var releaseUniqueLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// uniqueLockKey returns the redis key of the uniqueness lock.
+func uniqueLockKey(queue, taskName, key string) string {
+	return fmt.Sprintf("%s%s}:unique:%s:%s", uniqueLockKeyPrefix, queue, taskName, key)
+}
+
+// uniqueTaskID returns a new ID of the task holding the uniqueness lock,
+// so the queue server can find the lock by the task ID.
+func uniqueTaskID(lockKey string) string {
+	return fmt.Sprintf("%s:%d", lockKey, time.Now().UnixNano())
+}
+
+// uniqueTaskIDWithoutLock returns the ID of the task deduplicated by the key without the uniqueness lock.
+func uniqueTaskIDWithoutLock(taskName, key string) string {
+	return fmt.Sprintf("unique:%s:%s", taskName, key)
+}
+
+// uniqueLockKeyFromTaskID returns the redis key of the uniqueness lock held by the task with the given ID.
+func uniqueLockKeyFromTaskID(taskID string) (string, bool) {
+	if !strings.HasPrefix(taskID, uniqueLockKeyPrefix) || !strings.Contains(taskID, "}:unique:") {
+		return "", false
+	}
+	i := strings.LastIndexByte(taskID, ':')
+	if _, err := strconv.ParseInt(taskID[i+1:], 10, 64); err != nil {
+		return "", false
+	}
+	return taskID[:i], true
+}
+
+// taskIDFromOptions returns the task ID set by the given options, the last one wins.
+func taskIDFromOptions(opts []asynq.Option) string {
+	var id string
+	for _, opt := range opts {
+		if opt != nil && opt.Type() == asynq.TaskIDOpt {
+			if v, ok := opt.Value().(string); ok {
+				id = v
+			}
+		}
+	}
+	return id
+}
+
+// acquireUniqueLock acquires the uniqueness lock for the task with the given ID.
+// It returns ErrDuplicateTask if the lock is already held by another task.
+// The returned function releases the lock.
+func (e *Enqueuer) acquireUniqueLock(ctx context.Context, lockKey, taskID string, ttl time.Duration) (func(), error) {
+	ok, err := e.redis.SetNX(ctx, lockKey, taskID, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrDuplicateTask
+	}
+
+	return func() {
+		// Use a fresh context: the lock must be released even if the enqueue context is canceled.
+		_ = e.redis.Del(context.Background(), lockKey).Err()
+	}, nil
+}
+
+// releaseUniqueLock returns a middleware which releases the uniqueness lock held by the task once it succeeds,
+// so a task with the same key can be enqueued before the key TTL expires.
+// Other tasks are passed through as is.
+func (srv *QueueServer) releaseUniqueLock(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		taskID, _ := asynq.GetTaskID(ctx)
+		lockKey, ok := uniqueLockKeyFromTaskID(taskID)
+		if !ok {
+			return next.ProcessTask(ctx, t)
+		}
+
+		if err := next.ProcessTask(ctx, t); err != nil {
+			return err
+		}
+
+		// The error is ignored on purpose: the lock expires by its TTL anyway.
+		_ = releaseUniqueLockScript.Run(context.WithoutCancel(ctx), srv.redis, []string{lockKey}, taskID).Err()
+		return nil
+	})
+}
+
+// findOption returns the last option of the given type.
+func findOption[T asynq.Option](opts []asynq.Option) (T, bool) {
+	var (
+		res   T
+		found bool
+	)
+	for _, opt := range opts {
+		if o, ok := opt.(T); ok {
+			res, found = o, true
+		}
+	}
+	return res, found
+}
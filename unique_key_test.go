@@ -0,0 +1,131 @@
+package asyncer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type uniqueKeyerPayload struct{ ID string }
+
+func (p uniqueKeyerPayload) UniqueKey() string { return "custom:" + p.ID }
+
+func TestUniqueKeyFromPayload(t *testing.T) {
+	type tagged struct {
+		AccountID int64             `json:"account_id" asyncer:"unique"`
+		Month     string            `json:"month" asyncer:"unique"`
+		CreatedAt time.Time         `json:"created_at"`
+		Ref       *string           `asyncer:"unique"`
+		Tags      map[string]int    `asyncer:"unique"`
+		IDs       []int             `asyncer:"unique"`
+		Meta      map[string]string `json:"meta"`
+	}
+	ref := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		payload any
+		want    string
+	}{
+		{
+			name:    "no payload",
+			payload: nil,
+			want:    "",
+		},
+		{
+			name:    "not a struct",
+			payload: "text",
+			want:    "",
+		},
+		{
+			name:    "unique keyer",
+			payload: uniqueKeyerPayload{ID: "42"},
+			want:    "custom:42",
+		},
+		{
+			name: "tagged fields",
+			payload: tagged{
+				AccountID: 42,
+				Month:     "2026-01",
+				Ref:       ref("abc"),
+				Tags:      map[string]int{"b": 2, "a": 1},
+				IDs:       []int{1, 2},
+			},
+			want: `42:2026-01:abc:{"a":1,"b":2}:[1,2]`,
+		},
+		{
+			name:    "nil pointer field",
+			payload: &tagged{AccountID: 1},
+			want:    "1::null:null:null",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uniqueKeyFromPayload(tt.payload); got != tt.want {
+				t.Errorf("uniqueKeyFromPayload() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueKeyFromPayloadPointerFields(t *testing.T) {
+	type payload struct {
+		Ref *string `asyncer:"unique"`
+	}
+	a, b := "same", "same"
+
+	if ka, kb := uniqueKeyFromPayload(payload{Ref: &a}), uniqueKeyFromPayload(payload{Ref: &b}); ka != kb {
+		t.Errorf("equal payloads produce different keys: %q and %q", ka, kb)
+	}
+}
+
+func TestUniqueLockKeyFromTaskID(t *testing.T) {
+	lockKey := uniqueLockKey("default", "report:generate", "account:42")
+
+	tests := []struct {
+		name   string
+		taskID string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "task holding the lock",
+			taskID: uniqueTaskID(lockKey),
+			want:   lockKey,
+			wantOK: true,
+		},
+		{
+			name:   "task deduplicated without the lock",
+			taskID: uniqueTaskIDWithoutLock("report:generate", "account:42"),
+		},
+		{
+			name:   "random task ID",
+			taskID: "0b5d5a9c-5f3c-4f5e-9d6a-1f4c0f0e3a1b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := uniqueLockKeyFromTaskID(tt.taskID)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("uniqueLockKeyFromTaskID() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewEnqueueErrorIsNotWrappedTwice(t *testing.T) {
+	err := newEnqueueError("task", newEnqueueError("task", ErrDuplicateTask))
+
+	var eerr *EnqueueError
+	if !errors.As(err, &eerr) {
+		t.Fatalf("newEnqueueError() = %v, want *EnqueueError", err)
+	}
+	if errors.As(eerr.Err, new(*EnqueueError)) {
+		t.Errorf("newEnqueueError() wraps an *EnqueueError: %v", err)
+	}
+	if !errors.Is(err, ErrDuplicateTask) {
+		t.Errorf("newEnqueueError() = %v, want it to wrap ErrDuplicateTask", err)
+	}
+}